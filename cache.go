@@ -0,0 +1,152 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNotCached is returned if no value is cached for a parameter
+var ErrNotCached = errors.New("value not cached")
+
+// ValueSource of a cached value
+type ValueSource int
+
+// sources of cached values
+const (
+	// value received with an event
+	ValueSourceEvent ValueSource = iota
+	// value loaded from the CCU (e.g. getParamset)
+	ValueSourcePoll
+)
+
+// String returns the name of the source
+func (s ValueSource) String() string {
+	switch s {
+	case ValueSourceEvent:
+		return "event"
+	case ValueSourcePoll:
+		return "poll"
+	default:
+		return "unknown"
+	}
+}
+
+// CachedValue is the last known value of a parameter
+type CachedValue struct {
+	Value     interface{}
+	UpdatedAt time.Time
+	Source    ValueSource
+}
+
+// cacheValue stores the value of the parameter in the cache
+// -> returns the previous value and true if a value was cached before
+func (d *Device) cacheValue(key string, value interface{}, updatedAt time.Time, source ValueSource) (CachedValue, bool) {
+	d.valueMutex.Lock()
+	defer d.valueMutex.Unlock()
+
+	if d.values == nil {
+		d.values = make(map[string]CachedValue)
+	}
+	previous, ok := d.values[key]
+	d.values[key] = CachedValue{
+		Value:     value,
+		UpdatedAt: updatedAt,
+		Source:    source,
+	}
+	return previous, ok
+}
+
+// cacheValues stores all values loaded from the CCU in the cache
+// -> values received with an event after the request are kept
+// -> returns the parameters with changed values (values not cached before
+// are not seen as changed)
+func (d *Device) cacheValues(values map[string]interface{}, requestedAt time.Time) []string {
+	d.valueMutex.Lock()
+	defer d.valueMutex.Unlock()
+
+	if d.values == nil {
+		d.values = make(map[string]CachedValue, len(values))
+	}
+	now := time.Now()
+	var changed []string
+	for key, value := range values {
+		previous, ok := d.values[key]
+		if ok && previous.UpdatedAt.After(requestedAt) {
+			continue
+		}
+		d.values[key] = CachedValue{
+			Value:     value,
+			UpdatedAt: now,
+			Source:    ValueSourcePoll,
+		}
+		if ok && !reflect.DeepEqual(previous.Value, value) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// cachedValues returns a copy of the cache
+func (d *Device) cachedValues() map[string]CachedValue {
+	d.valueMutex.RLock()
+	defer d.valueMutex.RUnlock()
+
+	values := make(map[string]CachedValue, len(d.values))
+	for key, value := range d.values {
+		values[key] = value
+	}
+	return values
+}
+
+// CachedValue returns the last known value of the parameter
+// -> ErrNotCached if no value is known and the fallback is disabled
+// (see WithCacheFallback)
+func (d *Device) CachedValue(name string) (CachedValue, error) {
+	return d.CachedValueContext(context.Background(), name)
+}
+
+// CachedValueContext returns the last known value of the parameter and
+// aborts the fallback request if context is done
+func (d *Device) CachedValueContext(ctx context.Context, name string) (CachedValue, error) {
+	d.valueMutex.RLock()
+	value, ok := d.values[name]
+	d.valueMutex.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	values, err := d.CachedValuesContext(ctx)
+	if err != nil {
+		return CachedValue{}, err
+	}
+	value, ok = values[name]
+	if !ok {
+		return CachedValue{}, ErrNotCached
+	}
+	return value, nil
+}
+
+// CachedValues returns the last known values of the device
+// -> values are loaded from the CCU if nothing is cached and the fallback
+// is enabled (see WithCacheFallback)
+func (d *Device) CachedValues() (map[string]CachedValue, error) {
+	return d.CachedValuesContext(context.Background())
+}
+
+// CachedValuesContext returns the last known values of the device and
+// aborts the fallback request if context is done
+func (d *Device) CachedValuesContext(ctx context.Context) (map[string]CachedValue, error) {
+	values := d.cachedValues()
+	if len(values) > 0 || !d.cacheFallback || !d.HasValues() {
+		return values, nil
+	}
+
+	// GetValuesContext fills the cache
+	_, err := d.GetValuesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.cachedValues(), nil
+}
@@ -0,0 +1,174 @@
+package homematic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestValueSource_String(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal("event", ValueSourceEvent.String())
+	ass.Equal("poll", ValueSourcePoll.String())
+	ass.Equal("unknown", ValueSource(42).String())
+}
+
+func TestDevice_CachedValue(t *testing.T) {
+	ass := assert.New(t)
+
+	device := &Device{
+		Address:   "address",
+		ParamSets: []string{"VALUES"},
+		client: testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			ass.Fail("fallback should not be used")
+			return nil, nil
+		}),
+	}
+
+	_, err := device.CachedValue("STATE")
+	ass.Equal(ErrNotCached, err)
+	values, err := device.CachedValues()
+	ass.NoError(err)
+	ass.Empty(values)
+
+	now := time.Now()
+	_, ok := device.cacheValue("STATE", true, now, ValueSourceEvent)
+	ass.False(ok)
+	previous, ok := device.cacheValue("STATE", false, now, ValueSourceEvent)
+	ass.True(ok)
+	ass.Equal(true, previous.Value)
+
+	value, err := device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(CachedValue{
+		Value:     false,
+		UpdatedAt: now,
+		Source:    ValueSourceEvent,
+	}, value)
+}
+
+func TestDevice_CachedValues_fallback(t *testing.T) {
+	ass := assert.New(t)
+
+	var fail bool
+	device := &Device{
+		Address:       "address",
+		ParamSets:     []string{"VALUES"},
+		cacheFallback: true,
+		client: testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			ass.Equal("getParamset", method)
+			ass.Equal([]interface{}{"address", "VALUES"}, params)
+			if fail {
+				return nil, errors.New("failed")
+			}
+			return &rpc.Response{
+				Params: []interface{}{map[string]interface{}{
+					"STATE": true,
+				}},
+			}, nil
+		}),
+	}
+
+	fail = true
+	_, err := device.CachedValues()
+	ass.EqualError(err, "failed")
+
+	fail = false
+	value, err := device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(true, value.Value)
+	ass.Equal(ValueSourcePoll, value.Source)
+
+	_, err = device.CachedValue("LEVEL")
+	ass.Equal(ErrNotCached, err)
+}
+
+func TestDevice_GetValue_cache(t *testing.T) {
+	ass := assert.New(t)
+
+	device := new(Device)
+	device.client = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		// event is received while the value is requested
+		device.cacheValue("STATE", true, time.Now(), ValueSourceEvent)
+		return &rpc.Response{
+			Params: []interface{}{false},
+		}, nil
+	})
+
+	value, err := device.GetValue("STATE")
+	ass.NoError(err)
+	ass.Equal(false, value)
+
+	cached, err := device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(true, cached.Value)
+	ass.Equal(ValueSourceEvent, cached.Source)
+
+	// polled value is cached without newer event
+	device.client = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		return &rpc.Response{
+			Params: []interface{}{false},
+		}, nil
+	})
+	_, err = device.GetValue("STATE")
+	ass.NoError(err)
+	cached, err = device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(false, cached.Value)
+	ass.Equal(ValueSourcePoll, cached.Source)
+}
+
+func TestDevice_cacheValues(t *testing.T) {
+	ass := assert.New(t)
+
+	device := new(Device)
+	requestedAt := time.Now()
+	device.cacheValue("STATE", false, requestedAt.Add(time.Second), ValueSourceEvent)
+	device.cacheValue("LEVEL", 0.5, requestedAt.Add(-time.Second), ValueSourceEvent)
+
+	// newer event value is kept
+	changed := device.cacheValues(map[string]interface{}{
+		"STATE": true,
+		"LEVEL": 0.7,
+		"ERROR": 0,
+	}, requestedAt)
+	ass.Equal([]string{"LEVEL"}, changed)
+
+	value, err := device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(false, value.Value)
+	value, err = device.CachedValue("LEVEL")
+	ass.NoError(err)
+	ass.Equal(0.7, value.Value)
+	ass.Equal(ValueSourcePoll, value.Source)
+
+	// values not cached before are not changed
+	value, err = device.CachedValue("ERROR")
+	ass.NoError(err)
+	ass.Equal(0, value.Value)
+}
+
+func TestCCU_callbackEvent_cache(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	device := new(Device)
+	ccu.devices["address"] = device
+	_, fault := ccu.callbackEvent([]interface{}{
+		"id", "address", "STATE", true,
+	})
+	ass.Nil(fault)
+
+	value, err := device.CachedValue("STATE")
+	ass.NoError(err)
+	ass.Equal(true, value.Value)
+	ass.Equal(ValueSourceEvent, value.Source)
+	ass.False(value.UpdatedAt.IsZero())
+}
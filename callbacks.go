@@ -1,6 +1,8 @@
 package homematic
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	"github.com/spf13/cast"
@@ -17,6 +19,14 @@ func (c *CCU) handleCallback(method string, params []interface{}) ([]interface{}
 		return c.callbackListDevices()
 	case "newDevices":
 		return c.callbackNewDevices(params)
+	case "deleteDevices":
+		return c.callbackDeleteDevices(params)
+	case "updateDevice":
+		return c.callbackUpdateDevice(params)
+	case "replaceDevice":
+		return c.callbackReplaceDevice(params)
+	case "readdedDevice":
+		return c.callbackReaddedDevice(params)
 	}
 	return []interface{}{true}, nil
 }
@@ -30,22 +40,51 @@ func (c *CCU) callbackEvent(params []interface{}) ([]interface{}, *rpc.Fault) {
 		}
 	}
 
-	c.clientMutex.Lock()
-	c.lastClientEvent[cast.ToString(params[0])] = time.Now()
-	c.clientMutex.Unlock()
+	event := Event{
+		Interface:  cast.ToString(params[0]),
+		Address:    cast.ToString(params[1]),
+		Parameter:  cast.ToString(params[2]),
+		Value:      params[3],
+		ReceivedAt: time.Now(),
+	}
 
-	// if device is known trigger value change
-	c.deviceMutex.Lock()
-	device, ok := c.devices[cast.ToString(params[1])]
-	c.deviceMutex.Unlock()
+	c.setLastEvent(event.Interface, event.ReceivedAt)
+
+	// answer of ping is handled by supervisor
+	// -> PONG of own pings (caller id "<interface id>-<n>") is not published
+	if event.Address == "CENTRAL" && event.Parameter == "PONG" {
+		callerID := cast.ToString(event.Value)
+		if c.supervisor.pong(callerID) || strings.HasPrefix(callerID, event.Interface+"-") {
+			return nil, nil
+		}
+	}
+
+	c.deviceMutex.RLock()
+	device, ok := c.devices[event.Address]
+	var deviceTypes []string
 	if ok {
-		device.valueChanged(cast.ToString(params[2]), params[3])
+		deviceTypes = c.deviceTypes(device)
+	}
+	c.deviceMutex.RUnlock()
+
+	// events of unknown devices are also published
+	c.publishEvent(event, deviceTypes)
 
-		// check if event handling is working
-		c.checkEventHandling()
+	// other events of the interface are not related to a device
+	if event.Address == "CENTRAL" {
+		return nil, nil
+	}
+
+	// if device is known trigger value change
+	// -> handlers are called asynchronously to not block the callback
+	if ok {
+		device.cacheValue(event.Parameter, event.Value, event.ReceivedAt, ValueSourceEvent)
+		c.dispatcher.dispatch(event, func() {
+			device.valueChanged(event.Parameter, event.Value)
+		})
 	} else {
-		// if devices does not exist update device list
-		c.UpdateDevices(true)
+		// if devices does not exist update device list in background
+		c.supervisor.unknownAddress(event.Address, c.options.refreshInterval)
 	}
 	return nil, nil
 }
@@ -59,7 +98,7 @@ func (c *CCU) callbackListDevices() ([]interface{}, *rpc.Fault) {
 	for _, device := range c.devices {
 		data = append(data, map[string]interface{}{
 			"ADDRESS": device.Address,
-			"VERSION": device.Version,
+			"VERSION": device.GetVersion(),
 		})
 	}
 
@@ -78,39 +117,249 @@ func (c *CCU) callbackNewDevices(params []interface{}) ([]interface{}, *rpc.Faul
 	}
 
 	// check if client is known
-	c.clientMutex.Lock()
-	client, ok := c.rpcClients[cast.ToString(params[0])]
-	if !ok {
-		c.clientMutex.Unlock()
+	id := cast.ToString(params[0])
+	client, fault := c.callbackClient(id)
+	if fault != nil {
+		return nil, fault
+	}
+
+	// get device names from logic layer
+	// -> devices are added without names if names could not be loaded
+	deviceNames, _ := c.loadDeviceNames(context.Background())
+
+	c.deviceMutex.Lock()
+	// load each device
+	var addedDevices []*Device
+	descriptions, _ := params[1].([]interface{})
+	for _, data := range descriptions {
+		device, err := loadDevice(data)
+		if err != nil {
+			// ignore invalid devices
+			continue
+		}
+		device, added := c.addDevice(id, client, device, deviceNames[device.Address])
+		if added {
+			addedDevices = append(addedDevices, device)
+		}
+	}
+	c.deviceMutex.Unlock()
+
+	// notify waiting calls after all devices and channels are added
+	for _, device := range addedDevices {
+		c.newDeviceAdded(device)
+	}
+	return []interface{}{true}, nil
+}
+
+// handle deleteDevices callback
+func (c *CCU) callbackDeleteDevices(params []interface{}) ([]interface{}, *rpc.Fault) {
+	var addresses []string
+	if len(params) < 2 || rpc.Unmarshal(params[1], &addresses) != nil {
 		return nil, &rpc.Fault{
 			Code:   -1,
-			String: "invalid interface id",
+			String: "invalid deleteDevices call",
 		}
 	}
 
-	// get device names from logic layer
-	scriptData, err := c.scriptClient.Call(devNameScript)
+	c.deviceMutex.Lock()
+	defer c.deviceMutex.Unlock()
+	for _, address := range addresses {
+		c.removeDevice(address)
+	}
+	return []interface{}{true}, nil
+}
+
+// handle updateDevice callback
+func (c *CCU) callbackUpdateDevice(params []interface{}) ([]interface{}, *rpc.Fault) {
+	if len(params) < 3 {
+		return nil, &rpc.Fault{
+			Code:   -1,
+			String: "invalid updateDevice call",
+		}
+	}
+
+	id := cast.ToString(params[0])
+	client, fault := c.callbackClient(id)
+	if fault != nil {
+		return nil, fault
+	}
+
+	// reload description of device and its channels in background
+	address := cast.ToString(params[1])
+	c.supervisor.deviceUpdate(func(ctx context.Context) {
+		devices, err := loadDeviceTree(ctx, client, address)
+		if err != nil {
+			// ignore update of unknown devices
+			return
+		}
+		c.updateDevices(ctx, id, client, devices)
+	})
+	return []interface{}{true}, nil
+}
+
+// handle replaceDevice callback
+func (c *CCU) callbackReplaceDevice(params []interface{}) ([]interface{}, *rpc.Fault) {
+	if len(params) < 3 {
+		return nil, &rpc.Fault{
+			Code:   -1,
+			String: "invalid replaceDevice call",
+		}
+	}
+
+	id := cast.ToString(params[0])
+	client, fault := c.callbackClient(id)
+	if fault != nil {
+		return nil, fault
+	}
+
+	// replace device in background
+	oldAddress, newAddress := cast.ToString(params[1]), cast.ToString(params[2])
+	c.supervisor.deviceUpdate(func(ctx context.Context) {
+		c.replaceDevice(ctx, id, client, oldAddress, newAddress)
+	})
+	return []interface{}{true}, nil
+}
+
+// replaceDevice with the old address by the device with the new address
+func (c *CCU) replaceDevice(ctx context.Context, id string, client rpc.Client, oldAddress, newAddress string) {
+	// load new device and its channels
+	devices, err := loadDeviceTree(ctx, client, newAddress)
 	if err != nil {
-		c.clientMutex.Unlock()
-		// failed to get device names
-		return []interface{}{true}, nil
+		return
 	}
-	c.clientMutex.Unlock()
-	deviceNames := scriptData.GetMap("output")
+	deviceNames, _ := c.loadDeviceNames(ctx)
 
 	c.deviceMutex.Lock()
 	defer c.deviceMutex.Unlock()
-	// load each device
-	for _, data := range cast.ToSlice(params[1]) {
-		device := loadDevice(cast.ToStringMap(data))
-		_, ok := c.devices[device.Address]
-		if !ok {
-			device.client = client
-			c.devices[device.Address] = device
+
+	// remove old device with channels
+	oldDevice := c.devices[oldAddress]
+	if oldDevice != nil {
+		for _, address := range oldDevice.GetChildren() {
+			c.removeDevice(address)
 		}
+		c.removeDevice(oldDevice.Address)
+	}
 
-		c.devices[device.Address].nameChanged(deviceNames[device.Address])
+	// add new device with channels
+	newDevice, _ := c.addDevice(id, client, devices[0], deviceNames[devices[0].Address])
+	for _, device := range devices[1:] {
+		c.addDevice(id, client, device, deviceNames[device.Address])
 	}
 
+	if oldDevice != nil {
+		c.deviceReplaced(oldDevice, newDevice)
+	}
+}
+
+// handle readdedDevice callback
+func (c *CCU) callbackReaddedDevice(params []interface{}) ([]interface{}, *rpc.Fault) {
+	var addresses []string
+	if len(params) < 2 || rpc.Unmarshal(params[1], &addresses) != nil {
+		return nil, &rpc.Fault{
+			Code:   -1,
+			String: "invalid readdedDevice call",
+		}
+	}
+
+	id := cast.ToString(params[0])
+	client, fault := c.callbackClient(id)
+	if fault != nil {
+		return nil, fault
+	}
+
+	// addresses contain devices and channels
+	// -> descriptions are reloaded in background
+	c.supervisor.deviceUpdate(func(ctx context.Context) {
+		devices := make([]*Device, 0, len(addresses))
+		for _, address := range addresses {
+			device, err := getDeviceDescription(ctx, client, address)
+			if err != nil {
+				continue
+			}
+			devices = append(devices, device)
+		}
+		c.updateDevices(ctx, id, client, devices)
+	})
 	return []interface{}{true}, nil
 }
+
+// callbackClient returns the client of the interface id
+func (c *CCU) callbackClient(id string) (rpc.Client, *rpc.Fault) {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	client, ok := c.rpcClients[id]
+	if !ok {
+		return nil, &rpc.Fault{
+			Code:   -1,
+			String: "invalid interface id",
+		}
+	}
+	return client, nil
+}
+
+// updateDevices updates the description of known devices and adds
+// the unknown ones
+func (c *CCU) updateDevices(ctx context.Context, id string, client rpc.Client, devices []*Device) {
+	// names are only required for new devices
+	// -> loaded without lock to not block event handling
+	var unknown bool
+	c.deviceMutex.RLock()
+	for _, device := range devices {
+		if _, ok := c.devices[device.Address]; !ok {
+			unknown = true
+			break
+		}
+	}
+	c.deviceMutex.RUnlock()
+
+	var deviceNames map[string]string
+	if unknown {
+		deviceNames, _ = c.loadDeviceNames(ctx)
+	}
+
+	c.deviceMutex.Lock()
+	defer c.deviceMutex.Unlock()
+
+	for _, device := range devices {
+		existing, ok := c.devices[device.Address]
+		if ok {
+			existing.update(device)
+			c.deviceUpdated(existing)
+			continue
+		}
+		c.addDevice(id, client, device, deviceNames[device.Address])
+	}
+}
+
+// removeDevice from device list
+// Note: deviceMutex must be held by caller
+func (c *CCU) removeDevice(address string) {
+	device, ok := c.devices[address]
+	if !ok {
+		return
+	}
+	delete(c.devices, address)
+	c.supervisor.removePoll(address)
+	c.deviceRemoved(device)
+}
+
+// loadDeviceTree loads the description of the device and its channels
+// -> device is always the first entry
+func loadDeviceTree(ctx context.Context, client rpc.Client, address string) ([]*Device, error) {
+	device, err := getDeviceDescription(ctx, client, address)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []*Device{device}
+	for _, child := range device.Children {
+		channel, err := getDeviceDescription(ctx, client, child)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, channel)
+	}
+	return devices, nil
+}
@@ -98,6 +98,7 @@ func TestCCU_callbackNewDevices(t *testing.T) {
 		String: "invalid interface id",
 	}, fault)
 
+	// device is added without name if names can not be loaded
 	resp, fault = ccu.callbackNewDevices([]interface{}{
 		"go-rf",
 		[]interface{}{
@@ -109,6 +110,8 @@ func TestCCU_callbackNewDevices(t *testing.T) {
 	})
 	ass.Equal([]interface{}{true}, resp)
 	ass.Nil(fault)
+	ass.Contains(ccu.devices, "address")
+	ass.Equal("", ccu.devices["address"].GetName())
 
 	var scriptClient testScriptClient = func(script string) (script.Result, error) {
 		return map[string]string{
@@ -127,4 +130,5 @@ func TestCCU_callbackNewDevices(t *testing.T) {
 	})
 	ass.Equal([]interface{}{true}, resp)
 	ass.Nil(fault)
+	ass.Equal("testDevice", ccu.devices["address"].GetName())
 }
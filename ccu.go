@@ -1,137 +1,255 @@
 package homematic
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/spf13/cast"
-
+	"gitlab.com/bboehmke/homematic/jsonrpc"
 	"gitlab.com/bboehmke/homematic/rpc"
 	"gitlab.com/bboehmke/homematic/script"
 )
 
 // NewCCU creates a new connection to a CCU
 func NewCCU(address string) (*CCU, error) {
-	return NewCCUCustom(address, "go")
+	return NewCCUWithOptions(address)
 }
 
 // NewCCUCustom creates a new connection to a CCU with custom id
 func NewCCUCustom(address, id string) (*CCU, error) {
+	return NewCCUWithOptions(address, WithID(id))
+}
+
+// NewCCUWithOptions creates a new connection to a CCU with the given options
+func NewCCUWithOptions(address string, opts ...Option) (*CCU, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	ccu := &CCU{
-		rpcClients: map[string]rpc.Client{
-			fmt.Sprintf("%s-wired", id): rpc.NewClient(fmt.Sprintf("http://%s:2000/", address)),
-			fmt.Sprintf("%s-rf", id):    rpc.NewClient(fmt.Sprintf("http://%s:2001/", address)),
-			fmt.Sprintf("%s-hmip", id):  rpc.NewClient(fmt.Sprintf("http://%s:2010/", address)),
-		},
-		scriptClient: script.NewClient(fmt.Sprintf("http://%s:8181/", address)),
-		devices:      make(map[string]*Device),
+		options:         o,
+		rpcClients:      make(map[string]rpc.Client, len(o.interfaces)),
+		interfaces:      make(map[string]Interface, len(o.interfaces)),
+		interfaceStatus: make(map[string]InterfaceStatus, len(o.interfaces)),
+		scriptClient:    script.NewClient(o.url(address, o.scriptPort, o.scriptTLSPort), o.scriptClientOptions()...),
+		devices:         make(map[string]*Device),
+		eventStreams:    make(map[*eventStream]bool),
+		dispatcher:      newDispatcher(o.dispatchWorkers, o.dispatchQueueSize, o.panicHandler),
+		supervisor:      newSupervisor(),
+		hookQueue:       newHookQueue(o.panicHandler),
+
+		newDeviceWaiters: make(map[chan *Device]bool),
+	}
+	var binary bool
+	for _, iface := range o.interfaces {
+		id := fmt.Sprintf("%s-%s", o.id, iface.Name)
+		ccu.rpcClients[id] = o.interfaceClient(address, iface)
+		ccu.interfaces[id] = iface
+		binary = binary || iface.Binary
 	}
 	ccu.lastClientEvent = make(map[string]time.Time, len(ccu.rpcClients))
 
 	// prepare RPC server
 	var err error
-	ccu.rpcServer, err = rpc.NewServer(ccu.handleCallback)
+	ccu.rpcServer, err = rpc.NewServer(ccu.handleCallback,
+		rpc.WithListenAddress(o.listenAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	// binary RPC interfaces require a separate server
+	if binary {
+		ccu.binServer, err = rpc.NewBinServer(ccu.handleCallback,
+			rpc.WithListenAddress(o.binCallbackListenAddress()))
+	}
 	return ccu, err
 }
 
 // CCU represents a connection to a Homematic CCU
 type CCU struct {
+	options *options
+
 	rpcClients      map[string]rpc.Client
+	interfaces      map[string]Interface
+	interfaceStatus map[string]InterfaceStatus
 	rpcServer       *rpc.Server
+	binServer       *rpc.Server
+	supervisor      *supervisor
+
 	lastClientEvent map[string]time.Time
+	lastEventMutex  sync.Mutex
 
 	scriptClient script.Client
+	jsonClient   jsonrpc.Client
 	clientMutex  sync.RWMutex
 
 	devices     map[string]*Device
-	lastUpdate  time.Time
 	deviceMutex sync.RWMutex
+	lastUpdate  time.Time
+	updateMutex sync.Mutex
+
+	eventStreams map[*eventStream]bool
+	eventMutex   sync.Mutex
+	dispatcher   *dispatcher
+
+	hooks     deviceHooks
+	hookMutex sync.RWMutex
+	hookQueue *hookQueue
+
+	newDeviceWaiters map[chan *Device]bool
+	pairingMutex     sync.Mutex
 }
 
-// checkEventHandling for activity and re init if no events since long time
-func (c *CCU) checkEventHandling() error {
+// SetJSONRPCClient to load device names with the JSON RPC API
+// instead of the remote script port
+func (c *CCU) SetJSONRPCClient(client jsonrpc.Client) {
 	c.clientMutex.Lock()
 	defer c.clientMutex.Unlock()
 
-	// check only if server is running
-	if !c.rpcServer.IsRunning() {
-		return nil
+	c.jsonClient = client
+}
+
+// loadDeviceNames from logic layer
+// -> clients are loaded with lock but called without to not block callbacks
+func (c *CCU) loadDeviceNames(ctx context.Context) (map[string]string, error) {
+	c.clientMutex.RLock()
+	jsonClient, scriptClient := c.jsonClient, c.scriptClient
+	c.clientMutex.RUnlock()
+
+	return deviceNames(ctx, jsonClient, scriptClient)
+}
+
+// deviceNames loads the device names from logic layer
+// -> with the JSON RPC API if jsonClient is set
+func deviceNames(ctx context.Context, jsonClient jsonrpc.Client, scriptClient script.Client) (map[string]string, error) {
+	if jsonClient != nil {
+		return jsonrpc.DeviceNames(ctx, jsonClient)
 	}
 
-	for id, client := range c.rpcClients {
-		// only re init if no event since 10 minutes
-		if time.Since(c.lastClientEvent[id]) < time.Minute*10 {
-			continue
-		}
+	scriptData, err := scriptClient.CallContext(ctx, devNameScript)
+	if err != nil {
+		return nil, err
+	}
+	return scriptData.GetMap("output"), nil
+}
 
-		ip, err := client.LocalIP()
-		if err != nil {
-			return err
-		}
+// callbackURL sent to the interface with the init call
+func (c *CCU) callbackURL(id string, client rpc.Client) (string, error) {
+	scheme, server := "http", c.rpcServer
+	binary := c.interfaces[id].Binary
+	if binary {
+		scheme, server = "xmlrpc_bin", c.binServer
+	} else if c.options.callbackURL != "" {
+		return c.options.callbackURL, nil
+	}
+	port := strconv.Itoa(server.Port())
 
-		response, err := client.Call("init", []interface{}{
-			fmt.Sprintf("http://%s:%d", ip, c.rpcServer.Port()),
-			id,
-		})
+	if c.options.advertiseAddress != "" {
+		host, advertisePort, err := net.SplitHostPort(c.options.advertiseAddress)
 		if err != nil {
-			return err
+			// address contains only the host (IPv6 with or without brackets)
+			host = strings.TrimSuffix(strings.TrimPrefix(c.options.advertiseAddress, "["), "]")
+		} else if !binary {
+			port = advertisePort
 		}
+		return scheme + "://" + net.JoinHostPort(host, port), nil
+	}
 
-		if response.Fault != nil {
-			return errors.New(response.Fault.String)
-		}
-		c.lastClientEvent[id] = time.Now()
+	ip, err := client.LocalIP()
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return scheme + "://" + net.JoinHostPort(ip, port), nil
 }
 
 // Start event handling
 func (c *CCU) Start() error {
-	c.clientMutex.Lock()
-	defer c.clientMutex.Unlock()
+	return c.StartContext(context.Background())
+}
+
+// StartContext starts event handling and aborts init calls if context is done
+// -> returns a *StartError if the callback connection of an available
+// interface failed (event handling is started anyway)
+func (c *CCU) StartContext(ctx context.Context) error {
+	// dispatcher could be stopped by a previous Stop
+	c.dispatcher.start()
 
+	c.clientMutex.Lock()
 	c.rpcServer.Start()
+	if c.binServer != nil {
+		c.binServer.Start()
+	}
+	c.clientMutex.Unlock()
+	c.discoverInterfaces(ctx, true)
 
-	for id, client := range c.rpcClients {
-		ip, err := client.LocalIP()
-		if err != nil {
-			return err
-		}
+	// register only on available interfaces and check callback connection
+	// -> without lock to not block callbacks received before the PONG
+	err := c.checkInterfaces(ctx)
 
-		// ignore result -> handle all clients
-		_, _ = client.Call("init", []interface{}{
-			fmt.Sprintf("http://%s:%d", ip, c.rpcServer.Port()),
-			id,
-		})
-		c.lastClientEvent[id] = time.Now()
-	}
-	return nil
+	// check callback connection in background
+	// -> failed interfaces are registered again by supervisor
+	c.startSupervisor()
+	return err
 }
 
 // Stop event handling
 func (c *CCU) Stop() error {
-	c.clientMutex.Lock()
-	defer c.clientMutex.Unlock()
+	return c.StopContext(context.Background())
+}
+
+// StopContext stops event handling and aborts de-init calls if context is done
+func (c *CCU) StopContext(ctx context.Context) error {
+	c.stopSupervisor()
+
+	// de-init without lock to not block callbacks
+	c.clientMutex.RLock()
+	clients := c.availableClients()
+	c.clientMutex.RUnlock()
 
-	for _, client := range c.rpcClients {
-		ip, err := client.LocalIP()
+	for id, client := range clients {
+		url, err := c.callbackURL(id, client)
 		if err != nil {
 			return err
 		}
 
-		_, _ = client.Call("init", []interface{}{
-			fmt.Sprintf("http://%s:%d", ip, c.rpcServer.Port()),
+		_, _ = client.CallContext(ctx, "init", []interface{}{
+			url,
 			"",
 		})
 	}
-	return c.rpcServer.Stop()
+
+	c.clientMutex.Lock()
+	if c.binServer != nil {
+		err := c.binServer.Stop()
+		if err != nil {
+			c.clientMutex.Unlock()
+			return err
+		}
+	}
+	err := c.rpcServer.Stop()
+	c.clientMutex.Unlock()
+
+	// handle all queued events and hooks
+	// -> without lock as handlers may access the CCU
+	c.dispatcher.stop()
+	c.hookQueue.wait()
+	return err
 }
 
 // GetDevices from CCU
 func (c *CCU) GetDevices() (map[string]*Device, error) {
-	err := c.UpdateDevices(false)
+	return c.GetDevicesContext(context.Background())
+}
+
+// GetDevicesContext from CCU and abort update if context is done
+func (c *CCU) GetDevicesContext(ctx context.Context) (map[string]*Device, error) {
+	err := c.UpdateDevicesContext(ctx, false)
 	if err != nil {
 		return nil, err
 	}
@@ -144,54 +262,118 @@ func (c *CCU) GetDevices() (map[string]*Device, error) {
 
 // UpdateDevices currently known on CCU
 func (c *CCU) UpdateDevices(force bool) error {
-	err := c.checkEventHandling()
-	if err != nil {
-		return err
-	}
+	return c.UpdateDevicesContext(context.Background(), force)
+}
 
-	c.deviceMutex.Lock()
-	defer c.deviceMutex.Unlock()
+// UpdateDevicesContext currently known on CCU and abort if context is done
+func (c *CCU) UpdateDevicesContext(ctx context.Context, force bool) error {
+	c.updateMutex.Lock()
+	defer c.updateMutex.Unlock()
 
-	// update only every 10 minutes or if force is set
-	if !force && time.Since(c.lastUpdate) < time.Minute*10 {
+	// update only after refresh interval or if force is set
+	if !force && time.Since(c.lastUpdate) < c.options.refreshInterval {
 		return nil
 	}
 
-	// get device names from logic layer
-	scriptData, err := c.scriptClient.Call(devNameScript)
+	// get device names from logic layer and check unavailable interfaces
+	c.discoverInterfaces(ctx, false)
+	c.clientMutex.RLock()
+	clients := c.availableClients()
+	c.clientMutex.RUnlock()
+	deviceNames, err := c.loadDeviceNames(ctx)
 	if err != nil {
 		return err
 	}
-	deviceNames := scriptData.GetMap("output")
+	if len(clients) == 0 {
+		return errors.New("no interface available")
+	}
 
-	c.lastUpdate = time.Now()
-	currentDevices := make(map[string]bool, len(deviceNames))
-	// iterate over all interfaces
-	for _, client := range c.rpcClients {
-		response, err := client.Call("listDevices", nil)
+	// load devices of all available interfaces
+	// -> device list is not locked to not block event handling
+	interfaceDevices := make(map[string][]*Device, len(clients))
+	var listErr error
+	for id, client := range clients {
+		descriptions, err := listDevices(ctx, client)
 		if err != nil {
-			return err
+			// failed interface should not affect the others
+			c.clientMutex.Lock()
+			c.interfaceFailed(id, err)
+			c.clientMutex.Unlock()
+			listErr = err
+			continue
 		}
 
-		// load each device
-		for _, data := range cast.ToSlice(response.FirstParam()) {
-			device := loadDevice(cast.ToStringMap(data))
-			_, ok := c.devices[device.Address]
-			if !ok {
-				device.client = client
-				c.devices[device.Address] = device
+		devices := make([]*Device, 0, len(descriptions))
+		for _, data := range descriptions {
+			device, err := loadDevice(data)
+			if err != nil {
+				// ignore invalid devices -> should not hide the others
+				continue
 			}
-			currentDevices[device.Address] = true
+			devices = append(devices, device)
+		}
+		interfaceDevices[id] = devices
+	}
+	if len(interfaceDevices) == 0 {
+		return listErr
+	}
+	c.lastUpdate = time.Now()
 
-			c.devices[device.Address].nameChanged(deviceNames[device.Address])
+	c.deviceMutex.Lock()
+	defer c.deviceMutex.Unlock()
+
+	currentDevices := make(map[string]bool, len(deviceNames))
+	for id, devices := range interfaceDevices {
+		for _, device := range devices {
+			currentDevices[device.Address] = true
+			c.addDevice(id, clients[id], device, deviceNames[device.Address])
 		}
 	}
-	// cleanup
-	for address := range c.devices {
-		if !currentDevices[address] {
-			delete(c.devices, address)
+
+	// cleanup devices of listed interfaces
+	for address, device := range c.devices {
+		_, listed := interfaceDevices[device.Interface]
+		if !currentDevices[address] && (listed || device.Interface == "") {
+			c.removeDevice(address)
 		}
 	}
 
 	return nil
 }
+
+// addDevice to device list or update name of existing device
+// -> returns the device in the list and true if it was added
+// Note: deviceMutex must be held by caller
+func (c *CCU) addDevice(id string, client rpc.Client, device *Device, name string) (*Device, bool) {
+	existing, ok := c.devices[device.Address]
+	if ok {
+		oldName, changed := existing.nameChanged(name)
+		if changed {
+			c.deviceRenamed(existing, oldName)
+		}
+		return existing, false
+	}
+
+	device.client = client
+	device.Interface = id
+	device.cacheFallback = c.options.cacheFallback
+	device.nameChanged(name)
+	c.devices[device.Address] = device
+	c.deviceAdded(device)
+	return device, true
+}
+
+// listDevices returns the device descriptions of the interface
+func listDevices(ctx context.Context, client rpc.Client) ([]interface{}, error) {
+	response, err := client.CallContext(ctx, "listDevices", nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+
+	var descriptions []interface{}
+	err = rpc.Unmarshal(response.FirstParam(), &descriptions)
+	return descriptions, err
+}
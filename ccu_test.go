@@ -1,12 +1,15 @@
 package homematic
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"gitlab.com/bboehmke/homematic/jsonrpc"
 	"gitlab.com/bboehmke/homematic/rpc"
 	"gitlab.com/bboehmke/homematic/script"
 )
@@ -17,6 +20,16 @@ func (c testRpcClient) Call(method string, params []interface{}) (*rpc.Response,
 	return c(method, params)
 }
 
+func (c testRpcClient) CallContext(_ context.Context, method string, params []interface{}) (*rpc.Response, error) {
+	// interface discovery is answered for all test clients
+	if method == "system.listMethods" {
+		return &rpc.Response{
+			Params: []interface{}{[]interface{}{"init", "listDevices"}},
+		}, nil
+	}
+	return c(method, params)
+}
+
 func (c testRpcClient) LocalIP() (string, error) {
 	return "127.0.0.1", nil
 }
@@ -27,18 +40,42 @@ func (c testScriptClient) Call(script string) (script.Result, error) {
 	return c(script)
 }
 
-func TestCCU_checkEventHandling(t *testing.T) {
+func (c testScriptClient) CallContext(_ context.Context, script string) (script.Result, error) {
+	return c(script)
+}
+
+type testJSONClient struct {
+	jsonrpc.Client
+	devices []jsonrpc.Device
+}
+
+func (c *testJSONClient) CallContext(_ context.Context, method string, _ map[string]interface{}, result interface{}) error {
+	if method != "Device.listAllDetail" {
+		return errors.New("unknown method")
+	}
+	*(result.(*[]jsonrpc.Device)) = c.devices
+	return nil
+}
+
+func TestCCU_StartStop(t *testing.T) {
 	ass := assert.New(t)
 
 	ccu, err := NewCCU("127.0.0.1")
 	ass.NoError(err)
 
+	inits := make(chan []interface{}, 2)
 	var client testRpcClient = func(method string, params []interface{}) (*rpc.Response, error) {
-		ass.Equal("init", method)
-		ass.Equal([]interface{}{
-			fmt.Sprintf("http://127.0.0.1:%d", ccu.rpcServer.Port()),
-			"test",
-		}, params)
+		switch method {
+		case "init":
+			inits <- params
+		case "ping":
+			// answer ping with PONG event
+			go ccu.handleCallback("event", []interface{}{
+				"test", "CENTRAL", "PONG", params[0],
+			})
+		default:
+			ass.Fail("unexpected method", method)
+		}
 		return &rpc.Response{}, nil
 	}
 	ccu.rpcClients = map[string]rpc.Client{
@@ -46,23 +83,22 @@ func TestCCU_checkEventHandling(t *testing.T) {
 	}
 
 	ass.NoError(ccu.Start())
-
-	ccu.lastClientEvent = make(map[string]time.Time)
-
-	ass.NoError(ccu.checkEventHandling())
-
-	client = func(method string, params []interface{}) (*rpc.Response, error) {
-		ass.Equal("init", method)
-		ass.Equal([]interface{}{
-			fmt.Sprintf("http://127.0.0.1:%d", ccu.rpcServer.Port()),
-			"",
-		}, params)
-		return &rpc.Response{}, nil
-	}
-	ccu.rpcClients = map[string]rpc.Client{
-		"test": client,
+	ass.Equal([]interface{}{
+		fmt.Sprintf("http://127.0.0.1:%d", ccu.rpcServer.Port()),
+		"test",
+	}, <-inits)
+
+	// supervisor checks connection on start
+	for i := 0; i < 100 && ccu.Interfaces()["test"].State != ConnectionConnected; i++ {
+		time.Sleep(time.Millisecond * 10)
 	}
+	ass.Equal(ConnectionConnected, ccu.Interfaces()["test"].State)
+
 	ass.NoError(ccu.Stop())
+	ass.Equal([]interface{}{
+		fmt.Sprintf("http://127.0.0.1:%d", ccu.rpcServer.Port()),
+		"",
+	}, <-inits)
 }
 
 func TestCCU_GetDevices(t *testing.T) {
@@ -81,6 +117,12 @@ func TestCCU_GetDevices(t *testing.T) {
 						"ADDRESS": "address",
 						"TYPE":    "switch",
 					},
+					// invalid devices are ignored
+					"invalid",
+					map[string]interface{}{
+						"ADDRESS":  "address2",
+						"CHILDREN": "invalid",
+					},
 				},
 			},
 		}, nil
@@ -103,3 +145,52 @@ func TestCCU_GetDevices(t *testing.T) {
 	}, devices)
 	ass.Equal("testDevice", ccu.devices["address"].Name)
 }
+
+func TestCCU_SetJSONRPCClient(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	var rpcClient testRpcClient = func(method string, params []interface{}) (*rpc.Response, error) {
+		return &rpc.Response{
+			Params: []interface{}{
+				[]interface{}{
+					map[string]interface{}{
+						"ADDRESS": "address",
+					},
+					map[string]interface{}{
+						"ADDRESS": "address:1",
+						"PARENT":  "address",
+					},
+				},
+			},
+		}, nil
+	}
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": rpcClient,
+	}
+
+	var scriptClient testScriptClient = func(script string) (script.Result, error) {
+		ass.Fail("script client should not be used")
+		return nil, nil
+	}
+	ccu.scriptClient = scriptClient
+
+	ccu.SetJSONRPCClient(&testJSONClient{
+		devices: []jsonrpc.Device{{
+			Name:    "testDevice",
+			Address: "address",
+			Channels: []jsonrpc.Channel{{
+				Name:    "testChannel",
+				Address: "address:1",
+			}},
+		}},
+	})
+
+	devices, err := ccu.GetDevices()
+	ass.NoError(err)
+	ass.Len(devices, 2)
+	ass.Equal("testDevice", devices["address"].Name)
+	ass.Equal("testChannel", devices["address:1"].Name)
+}
@@ -1,7 +1,12 @@
 package homematic
 
 import (
+	"context"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/cast"
 
@@ -20,24 +25,54 @@ foreach(s_device, dom.GetObject(ID_DEVICES).EnumIDs()) {
 	}
 }`
 
+// deviceDescription as returned by listDevices
+type deviceDescription struct {
+	Type      string   `xmlrpc:"TYPE"`
+	Address   string   `xmlrpc:"ADDRESS"`
+	Children  []string `xmlrpc:"CHILDREN"`
+	Parent    string   `xmlrpc:"PARENT"`
+	ParamSets []string `xmlrpc:"PARAMSETS"`
+	Version   int      `xmlrpc:"VERSION"`
+	Flags     int32    `xmlrpc:"FLAGS"`
+}
+
+// getDeviceDescription loads the description of the device with the address
+func getDeviceDescription(ctx context.Context, client rpc.Client, address string) (*Device, error) {
+	response, err := client.CallContext(ctx, "getDeviceDescription", []interface{}{address})
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+	return loadDevice(response.FirstParam())
+}
+
 // loadDevice from received data
-func loadDevice(data map[string]interface{}) *Device {
-	flags := cast.ToInt32(data["FLAGS"])
+func loadDevice(data interface{}) (*Device, error) {
+	var description deviceDescription
+	err := rpc.Unmarshal(data, &description)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Device{
-		Type:      cast.ToString(data["TYPE"]),
-		Address:   cast.ToString(data["ADDRESS"]),
-		Children:  cast.ToStringSlice(data["CHILDREN"]),
-		Parent:    cast.ToString(data["PARENT"]),
-		ParamSets: cast.ToStringSlice(data["PARAMSETS"]),
-		Version:   cast.ToInt(data["VERSION"]),
+		Type:      description.Type,
+		Address:   description.Address,
+		Children:  description.Children,
+		Parent:    description.Parent,
+		ParamSets: description.ParamSets,
+		Version:   description.Version,
 
-		FlagVisible:    (flags & 0x01) != 0,
-		FlagInternal:   (flags & 0x02) != 0,
-		FlagDontdelete: (flags & 0x04) != 0,
-	}
+		FlagVisible:    (description.Flags & 0x01) != 0,
+		FlagInternal:   (description.Flags & 0x02) != 0,
+		FlagDontdelete: (description.Flags & 0x04) != 0,
+	}, nil
 }
 
 // Device of CCU
+// -> description fields are changed by updateDevice and readdedDevice
+// callbacks, use the getters (e.g. GetType) while events are handled
 type Device struct {
 	client            rpc.Client
 	valuesDescription map[string]ParameterDescription
@@ -48,6 +83,9 @@ type Device struct {
 	Address string
 	Version int
 
+	// id of the interface the device belongs to (e.g. "go-rf")
+	Interface string
+
 	Children  []string
 	Parent    string
 	ParamSets []string
@@ -57,27 +95,67 @@ type Device struct {
 	FlagDontdelete bool
 
 	onValueChange func(key string, value interface{})
+	handlers      []*Subscription
+
+	values        map[string]CachedValue
+	valueMutex    sync.RWMutex
+	cacheFallback bool
+	pollInterval  time.Duration
+
+	// set if putParamset is not supported by the interface
+	noPutParamset int32
 }
 
-// nameChanged updates device name
-func (d *Device) nameChanged(name string) {
+// nameChanged updates device name and returns the previous name
+// and true if the name changed
+func (d *Device) nameChanged(name string) (string, bool) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
+	oldName := d.Name
 	d.Name = name
+	return oldName, oldName != name
 }
 
-// valueChanged calls OnValueChange function if set
+// update description of device with the one of the loaded device
+func (d *Device) update(device *Device) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.Type = device.Type
+	d.Version = device.Version
+	d.Children = device.Children
+	d.Parent = device.Parent
+	d.ParamSets = device.ParamSets
+	d.FlagVisible = device.FlagVisible
+	d.FlagInternal = device.FlagInternal
+	d.FlagDontdelete = device.FlagDontdelete
+
+	// reload parameter description on next request
+	d.valuesDescription = nil
+}
+
+// valueChanged calls OnValueChange function and all matching handlers
 func (d *Device) valueChanged(key string, value interface{}) {
 	d.mutex.RLock()
-	defer d.mutex.RUnlock()
+	onValueChange := d.onValueChange
+	handlers := d.handlers
+	d.mutex.RUnlock()
 
-	if d.onValueChange != nil {
-		d.onValueChange(key, value)
+	// handlers are called without lock -> can be cancelled inside handler
+	if onValueChange != nil {
+		onValueChange(key, value)
+	}
+	for _, subscription := range handlers {
+		if subscription.matches(key) {
+			subscription.handler(key, value)
+		}
 	}
 }
 
-// nameChanged updates device name
+// SetValueChangedHandler sets the handler called on value changes
+// -> replaces the previous handler set with this function
+// -> handlers added with AddValueChangedHandler are not affected
 func (d *Device) SetValueChangedHandler(handler func(key string, value interface{})) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
@@ -85,14 +163,69 @@ func (d *Device) SetValueChangedHandler(handler func(key string, value interface
 	d.onValueChange = handler
 }
 
-// HasValues returns true if device has values
-func (d *Device) HasValues() bool {
-	for _, p := range d.ParamSets {
-		if p == "VALUES" {
-			return true
+// AddValueChangedHandler adds a handler called on value changes of the
+// given parameters (all parameters if none given)
+func (d *Device) AddValueChangedHandler(handler func(key string, value interface{}), parameters ...string) *Subscription {
+	subscription := &Subscription{
+		device:  d,
+		handler: handler,
+	}
+	if len(parameters) > 0 {
+		subscription.parameters = make(map[string]bool, len(parameters))
+		for _, parameter := range parameters {
+			subscription.parameters[parameter] = true
 		}
 	}
-	return false
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// copy on write -> valueChanged can use the slice without lock
+	handlers := make([]*Subscription, len(d.handlers), len(d.handlers)+1)
+	copy(handlers, d.handlers)
+	d.handlers = append(handlers, subscription)
+	return subscription
+}
+
+// removeHandler removes the subscription from the handlers
+func (d *Device) removeHandler(subscription *Subscription) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	handlers := make([]*Subscription, 0, len(d.handlers))
+	for _, s := range d.handlers {
+		if s != subscription {
+			handlers = append(handlers, s)
+		}
+	}
+	d.handlers = handlers
+}
+
+// Subscription of a value changed handler of a device
+type Subscription struct {
+	device     *Device
+	handler    func(key string, value interface{})
+	parameters map[string]bool
+	cancelled  int32
+}
+
+// matches returns true if the subscription handles the parameter
+func (s *Subscription) matches(key string) bool {
+	if atomic.LoadInt32(&s.cancelled) != 0 {
+		return false
+	}
+	return s.parameters == nil || s.parameters[key]
+}
+
+// Cancel the subscription -> handler is not called anymore
+func (s *Subscription) Cancel() {
+	atomic.StoreInt32(&s.cancelled, 1)
+	s.device.removeHandler(s)
+}
+
+// HasValues returns true if device has values
+func (d *Device) HasValues() bool {
+	return d.HasParamset(ParamsetValues)
 }
 
 // GetName updates device name
@@ -103,54 +236,209 @@ func (d *Device) GetName() string {
 	return d.Name
 }
 
+// GetType of the device
+func (d *Device) GetType() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.Type
+}
+
+// GetVersion of the device description
+func (d *Device) GetVersion() int {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.Version
+}
+
+// GetParent returns the address of the parent device (empty for devices)
+func (d *Device) GetParent() string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.Parent
+}
+
+// GetChildren returns the addresses of the channels of the device
+func (d *Device) GetChildren() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return append([]string(nil), d.Children...)
+}
+
+// GetParamSets returns the keys of the paramsets of the device
+func (d *Device) GetParamSets() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return append([]string(nil), d.ParamSets...)
+}
+
 // GetValues of a device
 func (d *Device) GetValues() (map[string]interface{}, error) {
-	response, err := d.client.Call(
+	return d.GetValuesContext(context.Background())
+}
+
+// GetValuesContext of a device and abort if context is done
+func (d *Device) GetValuesContext(ctx context.Context) (map[string]interface{}, error) {
+	requestedAt := time.Now()
+	response, err := d.client.CallContext(ctx,
 		"getParamset",
 		[]interface{}{d.Address, "VALUES"})
 	if err != nil {
 		return nil, err
 	}
-	return cast.ToStringMap(response.FirstParam()), nil
+	values := cast.ToStringMap(response.FirstParam())
+	d.cacheValues(values, requestedAt)
+	return values, nil
 }
 
 // GetValue of a device with the given name
 func (d *Device) GetValue(name string) (interface{}, error) {
-	response, err := d.client.Call(
+	return d.GetValueContext(context.Background(), name)
+}
+
+// GetValueContext of a device with the given name and abort if context is done
+func (d *Device) GetValueContext(ctx context.Context, name string) (interface{}, error) {
+	requestedAt := time.Now()
+	response, err := d.client.CallContext(ctx,
 		"getValue",
 		[]interface{}{d.Address, name})
 	if err != nil {
 		return nil, err
 	}
+	if response.Fault == nil {
+		// values received with an event during the request are kept
+		d.cacheValues(map[string]interface{}{name: response.FirstParam()}, requestedAt)
+	}
 	return response.FirstParam(), nil
 }
 
 // SetValue of a device with given name
 func (d *Device) SetValue(name string, value interface{}) error {
-	_, err := d.client.Call(
+	return d.SetValueContext(context.Background(), name, value)
+}
+
+// SetValueContext of a device with given name and abort if context is done
+func (d *Device) SetValueContext(ctx context.Context, name string, value interface{}) error {
+	_, err := d.client.CallContext(ctx,
 		"setValue",
 		[]interface{}{d.Address, name, value})
 	return err
 }
 
+// SetValues of a device with a single putParamset call
+// -> all parameters are validated before sending
+// -> falls back to one setValue call per parameter if putParamset is not
+// supported (LEVEL and STATE are set last to apply e.g. RAMP_TIME)
+func (d *Device) SetValues(values map[string]interface{}) error {
+	return d.SetValuesContext(context.Background(), values)
+}
+
+// SetValuesContext of a device with a single putParamset call and abort if
+// context is done
+func (d *Device) SetValuesContext(ctx context.Context, values map[string]interface{}) error {
+	descriptions, err := d.GetValuesDescriptionContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	var invalid []string
+	for name := range values {
+		names = append(names, name)
+		if !descriptions[name].OperationWrite {
+			invalid = append(invalid, name)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return &ParameterError{
+			Paramset:   ParamsetValues,
+			Parameters: invalid,
+		}
+	}
+
+	if atomic.LoadInt32(&d.noPutParamset) == 0 {
+		err = d.PutParamsetContext(ctx, ParamsetValues, values)
+		if !unknownMethod(err) {
+			return err
+		}
+	}
+
+	// set values one by one if putParamset is not supported
+	sort.Slice(names, func(i, j int) bool {
+		iLast, jLast := applyParameters[names[i]], applyParameters[names[j]]
+		if iLast != jLast {
+			return jLast
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		response, err := d.client.CallContext(ctx,
+			"setValue",
+			[]interface{}{d.Address, name, values[name]})
+		if err != nil {
+			return err
+		}
+		if response.Fault != nil {
+			return response.Fault
+		}
+	}
+
+	// putParamset is not supported if setValue works
+	atomic.StoreInt32(&d.noPutParamset, 1)
+	return nil
+}
+
+// unknownMethod returns true if the error is the fault of an interface
+// that does not support the called method
+// -> xmlrpc-c based interfaces use -32601, others only a message
+func unknownMethod(err error) bool {
+	fault, ok := err.(*rpc.Fault)
+	if !ok {
+		return false
+	}
+	if fault.Code == -32601 {
+		return true
+	}
+	message := strings.ToLower(fault.String)
+	return strings.Contains(message, "method") &&
+		(strings.Contains(message, "unknown") || strings.Contains(message, "not found"))
+}
+
+// parameters that apply other parameters and are set last by SetValues
+var applyParameters = map[string]bool{
+	"LEVEL": true,
+	"STATE": true,
+}
+
 // GetValuesDescription for this device
 func (d *Device) GetValuesDescription() (map[string]ParameterDescription, error) {
+	return d.GetValuesDescriptionContext(context.Background())
+}
+
+// GetValuesDescriptionContext for this device and abort if context is done
+func (d *Device) GetValuesDescriptionContext(ctx context.Context) (map[string]ParameterDescription, error) {
+	d.mutex.RLock()
+	descriptions := d.valuesDescription
+	d.mutex.RUnlock()
+
 	// load on first call
-	if d.valuesDescription == nil {
-		response, err := d.client.Call(
-			"getParamsetDescription",
-			[]interface{}{d.Address, "VALUES"})
+	if descriptions == nil {
+		var err error
+		descriptions, err = d.GetParamsetDescriptionContext(ctx, ParamsetValues)
 		if err != nil {
 			return nil, err
 		}
 
-		rawData := cast.ToStringMap(response.FirstParam())
-		d.valuesDescription = make(map[string]ParameterDescription, len(rawData))
-		for key, value := range rawData {
-			d.valuesDescription[key] = loadParameterDescription(value)
-		}
+		d.mutex.Lock()
+		d.valuesDescription = descriptions
+		d.mutex.Unlock()
 	}
-	return d.valuesDescription, nil
+	return descriptions, nil
 }
 
 // ParameterDescription contains information about a parameter
@@ -173,27 +461,42 @@ type ParameterDescription struct {
 	FlagSticky    bool
 }
 
+// parameterDescription as returned by getParamsetDescription
+type parameterDescription struct {
+	ID         string      `xmlrpc:"ID"`
+	Default    interface{} `xmlrpc:"DEFAULT"`
+	Type       string      `xmlrpc:"TYPE"`
+	Unit       string      `xmlrpc:"UNIT"`
+	TabOrder   int         `xmlrpc:"TAB_ORDER"`
+	ValueList  []string    `xmlrpc:"VALUE_LIST"`
+	Operations int32       `xmlrpc:"OPERATIONS"`
+	Flags      int32       `xmlrpc:"FLAGS"`
+}
+
 // loadParameterDescription from received data
-func loadParameterDescription(data interface{}) ParameterDescription {
-	dataMap := cast.ToStringMap(data)
-	operations := cast.ToInt32(dataMap["OPERATIONS"])
-	flags := cast.ToInt32(dataMap["FLAGS"])
-	return ParameterDescription{
-		ID:        cast.ToString(dataMap["ID"]),
-		Default:   dataMap["DEFAULT"],
-		Type:      cast.ToString(dataMap["TYPE"]),
-		Unit:      cast.ToString(dataMap["UNIT"]),
-		TabOrder:  cast.ToInt(dataMap["TAB_ORDER"]),
-		ValueList: cast.ToStringSlice(dataMap["VALUE_LIST"]),
-
-		OperationRead:  (operations & 0x01) != 0,
-		OperationWrite: (operations & 0x02) != 0,
-		OperationEvent: (operations & 0x04) != 0,
-
-		FlagVisible:   (flags & 0x01) != 0,
-		FlagInternal:  (flags & 0x02) != 0,
-		FlagTransform: (flags & 0x04) != 0,
-		FlagService:   (flags & 0x08) != 0,
-		FlagSticky:    (flags & 0x10) != 0,
+func loadParameterDescription(data interface{}) (ParameterDescription, error) {
+	var description parameterDescription
+	err := rpc.Unmarshal(data, &description)
+	if err != nil {
+		return ParameterDescription{}, err
 	}
+
+	return ParameterDescription{
+		ID:        description.ID,
+		Default:   description.Default,
+		Type:      description.Type,
+		Unit:      description.Unit,
+		TabOrder:  description.TabOrder,
+		ValueList: description.ValueList,
+
+		OperationRead:  (description.Operations & 0x01) != 0,
+		OperationWrite: (description.Operations & 0x02) != 0,
+		OperationEvent: (description.Operations & 0x04) != 0,
+
+		FlagVisible:   (description.Flags & 0x01) != 0,
+		FlagInternal:  (description.Flags & 0x02) != 0,
+		FlagTransform: (description.Flags & 0x04) != 0,
+		FlagService:   (description.Flags & 0x08) != 0,
+		FlagSticky:    (description.Flags & 0x10) != 0,
+	}, nil
 }
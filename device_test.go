@@ -17,8 +17,64 @@ func TestDevice_nameChanged(t *testing.T) {
 	}
 
 	ass.Equal("aaa", device.Name)
-	device.nameChanged("bbb")
+	oldName, changed := device.nameChanged("bbb")
 	ass.Equal("bbb", device.Name)
+	ass.Equal("aaa", oldName)
+	ass.True(changed)
+
+	_, changed = device.nameChanged("bbb")
+	ass.False(changed)
+}
+
+func TestDevice_update(t *testing.T) {
+	ass := assert.New(t)
+
+	device := &Device{
+		Name:              "name",
+		Address:           "address",
+		Type:              "old",
+		Version:           1,
+		valuesDescription: map[string]ParameterDescription{},
+	}
+	device.update(&Device{
+		Address:     "address",
+		Type:        "new",
+		Version:     2,
+		ParamSets:   []string{"VALUES"},
+		FlagVisible: true,
+	})
+	ass.Equal("name", device.Name)
+	ass.Equal("new", device.Type)
+	ass.Equal(2, device.Version)
+	ass.Equal([]string{"VALUES"}, device.ParamSets)
+	ass.True(device.FlagVisible)
+	ass.Nil(device.valuesDescription)
+
+	// getters can be used while the device is updated
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		device.update(&Device{
+			Type:      "other",
+			Version:   3,
+			Parent:    "parent",
+			Children:  []string{"child"},
+			ParamSets: []string{"MASTER"},
+		})
+	}()
+	device.HasValues()
+	device.GetType()
+	device.GetVersion()
+	device.GetParent()
+	device.GetChildren()
+	<-done
+
+	ass.Equal("other", device.GetType())
+	ass.Equal(3, device.GetVersion())
+	ass.Equal("parent", device.GetParent())
+	ass.Equal([]string{"child"}, device.GetChildren())
+	ass.Equal([]string{"MASTER"}, device.GetParamSets())
+	ass.False(device.HasValues())
 }
 
 func TestDevice_valueChanged(t *testing.T) {
@@ -33,6 +89,67 @@ func TestDevice_valueChanged(t *testing.T) {
 	device.valueChanged("aaa", "bbb")
 }
 
+func TestDevice_AddValueChangedHandler(t *testing.T) {
+	ass := assert.New(t)
+
+	device := new(Device)
+
+	var legacy, all, state, temperature []interface{}
+	device.SetValueChangedHandler(func(key string, value interface{}) {
+		legacy = append(legacy, value)
+	})
+	allSubscription := device.AddValueChangedHandler(func(key string, value interface{}) {
+		all = append(all, value)
+	})
+	device.AddValueChangedHandler(func(key string, value interface{}) {
+		ass.Equal("STATE", key)
+		state = append(state, value)
+	}, "STATE")
+	device.AddValueChangedHandler(func(key string, value interface{}) {
+		temperature = append(temperature, value)
+	}, "ACTUAL_TEMPERATURE", "SET_TEMPERATURE")
+
+	device.valueChanged("STATE", true)
+	device.valueChanged("ACTUAL_TEMPERATURE", 21.5)
+	device.valueChanged("LEVEL", 0.5)
+
+	ass.Equal([]interface{}{true, 21.5, 0.5}, legacy)
+	ass.Equal([]interface{}{true, 21.5, 0.5}, all)
+	ass.Equal([]interface{}{true}, state)
+	ass.Equal([]interface{}{21.5}, temperature)
+
+	// cancelled handler is not called anymore
+	allSubscription.Cancel()
+	allSubscription.Cancel()
+	device.valueChanged("STATE", false)
+	ass.Equal([]interface{}{true, 21.5, 0.5}, all)
+	ass.Equal([]interface{}{true, false}, state)
+	ass.Len(device.handlers, 2)
+
+	// setter replaces only the legacy handler
+	device.SetValueChangedHandler(nil)
+	device.valueChanged("SET_TEMPERATURE", 20.0)
+	ass.Equal([]interface{}{true, 21.5, 0.5, false}, legacy)
+	ass.Equal([]interface{}{21.5, 20.0}, temperature)
+}
+
+func TestDevice_AddValueChangedHandler_cancelInHandler(t *testing.T) {
+	ass := assert.New(t)
+
+	device := new(Device)
+
+	var calls int
+	var subscription *Subscription
+	subscription = device.AddValueChangedHandler(func(key string, value interface{}) {
+		calls++
+		subscription.Cancel()
+	})
+	device.valueChanged("STATE", true)
+	device.valueChanged("STATE", false)
+	ass.Equal(1, calls)
+	ass.Empty(device.handlers)
+}
+
 func TestDevice_HasValues(t *testing.T) {
 	ass := assert.New(t)
 
@@ -123,6 +240,81 @@ func TestDevice_SetValue(t *testing.T) {
 	ass.EqualError(err, "test")
 }
 
+func TestDevice_SetValues(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls [][]interface{}
+	putParamset := true
+	device := &Device{
+		Address: "address",
+		valuesDescription: map[string]ParameterDescription{
+			"LEVEL":     {OperationWrite: true},
+			"ON_TIME":   {OperationWrite: true},
+			"RAMP_TIME": {OperationWrite: true},
+			"WORKING":   {OperationRead: true},
+		},
+		client: testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			calls = append(calls, append([]interface{}{method}, params...))
+			if method == "putParamset" && params[2].(map[string]interface{})["LEVEL"] == -1.0 {
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -5, String: "Unknown Parameter value for value key: LEVEL"},
+				}, nil
+			}
+			if method == "putParamset" && !putParamset {
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -1, String: "unknown method"},
+				}, nil
+			}
+			return &rpc.Response{}, nil
+		}),
+	}
+	values := map[string]interface{}{
+		"LEVEL":     0.5,
+		"RAMP_TIME": 2.0,
+		"ON_TIME":   60.0,
+	}
+
+	// invalid parameters are not sent
+	err := device.SetValues(map[string]interface{}{
+		"LEVEL":   0.5,
+		"WORKING": true,
+		"UNKNOWN": 1,
+	})
+	ass.EqualError(err, "invalid parameter UNKNOWN, WORKING in paramset VALUES")
+	ass.Empty(calls)
+
+	ass.NoError(device.SetValues(values))
+	ass.Equal([][]interface{}{
+		{"putParamset", "address", "VALUES", values},
+	}, calls)
+
+	// other faults are returned without fallback
+	calls = nil
+	invalid := map[string]interface{}{"LEVEL": -1.0}
+	ass.EqualError(device.SetValues(invalid), "Unknown Parameter value for value key: LEVEL (-5)")
+	ass.Equal([][]interface{}{
+		{"putParamset", "address", "VALUES", invalid},
+	}, calls)
+
+	// fallback to setValue
+	calls = nil
+	putParamset = false
+	ass.NoError(device.SetValues(values))
+	ass.Equal([][]interface{}{
+		{"putParamset", "address", "VALUES", values},
+		{"setValue", "address", "ON_TIME", 60.0},
+		{"setValue", "address", "RAMP_TIME", 2.0},
+		{"setValue", "address", "LEVEL", 0.5},
+	}, calls)
+
+	// putParamset is not used again
+	calls = nil
+	ass.NoError(device.SetValues(map[string]interface{}{"LEVEL": 1.0}))
+	ass.Equal([][]interface{}{
+		{"setValue", "address", "LEVEL", 1.0},
+	}, calls)
+}
+
 func TestDevice_GetValuesDescription(t *testing.T) {
 	ass := assert.New(t)
 
@@ -178,3 +370,33 @@ func TestDevice_GetValuesDescription(t *testing.T) {
 	}, values)
 
 }
+
+func TestLoadDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	device, err := loadDevice(map[string]interface{}{
+		"TYPE":      "HM-LC-Sw1-FM",
+		"ADDRESS":   "OEQ1234567",
+		"CHILDREN":  []interface{}{"OEQ1234567:0", "OEQ1234567:1"},
+		"PARENT":    "",
+		"PARAMSETS": []interface{}{"MASTER"},
+		"VERSION":   int32(5),
+		"FLAGS":     int32(0x01 + 0x04),
+	})
+	ass.NoError(err)
+	ass.Equal(&Device{
+		Type:      "HM-LC-Sw1-FM",
+		Address:   "OEQ1234567",
+		Children:  []string{"OEQ1234567:0", "OEQ1234567:1"},
+		ParamSets: []string{"MASTER"},
+		Version:   5,
+
+		FlagVisible:    true,
+		FlagDontdelete: true,
+	}, device)
+
+	_, err = loadDevice(map[string]interface{}{
+		"CHILDREN": "invalid",
+	})
+	ass.EqualError(err, "cannot unmarshal string into []string (CHILDREN)")
+}
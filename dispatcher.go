@@ -0,0 +1,200 @@
+package homematic
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// DispatcherStats of the asynchronous event dispatch
+type DispatcherStats struct {
+	// Queued events waiting for dispatch
+	Queued int
+	// Capacity of all queues
+	Capacity int
+	// HighWater is the maximal number of queued events of a single queue
+	HighWater int
+	// Dispatched events
+	Dispatched uint64
+	// Dropped events because the queue of the device was full
+	Dropped uint64
+	// Panics recovered in event handlers
+	Panics uint64
+}
+
+// dispatchTask of a single event
+type dispatchTask struct {
+	event   Event
+	handler func()
+}
+
+// dispatcher calls event handlers asynchronously
+// -> events of the same address are handled in order by the same worker
+type dispatcher struct {
+	queueSize    int
+	panicHandler func(event Event, recovered interface{})
+
+	queues  []chan dispatchTask
+	running bool
+	stopped bool
+	wg      sync.WaitGroup
+	mutex   sync.RWMutex
+
+	highWater  int64
+	dispatched uint64
+	dropped    uint64
+	panics     uint64
+}
+
+// newDispatcher with the given number of workers and queue size per worker
+func newDispatcher(workers, queueSize int, panicHandler func(event Event, recovered interface{})) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	// unbuffered queues would drop nearly all events
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &dispatcher{
+		queueSize:    queueSize,
+		panicHandler: panicHandler,
+		queues:       make([]chan dispatchTask, workers),
+	}
+}
+
+// dispatch handler of event or drop it if the queue is full
+// -> workers are started on first dispatch
+// -> events are dropped after stop until the dispatcher is started again
+func (d *dispatcher) dispatch(event Event, handler func()) bool {
+	d.mutex.RLock()
+	if !d.running && !d.stopped {
+		d.mutex.RUnlock()
+		d.mutex.Lock()
+		d.startWorkers()
+		d.mutex.Unlock()
+		d.mutex.RLock()
+	}
+	defer d.mutex.RUnlock()
+
+	if !d.running {
+		atomic.AddUint64(&d.dropped, 1)
+		return false
+	}
+
+	queue := d.queues[d.queueIndex(event.Address)]
+	select {
+	case queue <- dispatchTask{event: event, handler: handler}:
+		d.updateHighWater(len(queue))
+		return true
+	default:
+		atomic.AddUint64(&d.dropped, 1)
+		return false
+	}
+}
+
+// queueIndex for the address
+func (d *dispatcher) queueIndex(address string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(address))
+	return int(hash.Sum32() % uint32(len(d.queues)))
+}
+
+// updateHighWater mark of queue length
+func (d *dispatcher) updateHighWater(length int) {
+	for {
+		current := atomic.LoadInt64(&d.highWater)
+		if int64(length) <= current ||
+			atomic.CompareAndSwapInt64(&d.highWater, current, int64(length)) {
+			return
+		}
+	}
+}
+
+// start workers if not running (also after stop)
+func (d *dispatcher) start() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.stopped = false
+	d.startWorkers()
+}
+
+// startWorkers if not running and not stopped
+// Note: mutex must be held by caller
+func (d *dispatcher) startWorkers() {
+	if d.running || d.stopped {
+		return
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan dispatchTask, d.queueSize)
+		d.wg.Add(1)
+		go d.worker(d.queues[i])
+	}
+	d.running = true
+}
+
+// stop workers after all queued events are handled
+// -> workers are not started again by dispatch
+func (d *dispatcher) stop() {
+	d.mutex.Lock()
+	d.stopped = true
+	if !d.running {
+		d.mutex.Unlock()
+		return
+	}
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.running = false
+	d.mutex.Unlock()
+
+	d.wg.Wait()
+}
+
+// worker handles tasks of queue until it is closed
+func (d *dispatcher) worker(queue chan dispatchTask) {
+	defer d.wg.Done()
+	for task := range queue {
+		d.handle(task)
+	}
+}
+
+// handle task and recover from panics in handler
+func (d *dispatcher) handle(task dispatchTask) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			atomic.AddUint64(&d.panics, 1)
+			if d.panicHandler != nil {
+				d.panicHandler(task.event, recovered)
+			}
+		}
+	}()
+
+	atomic.AddUint64(&d.dispatched, 1)
+	task.handler()
+}
+
+// stats of the dispatcher
+func (d *dispatcher) stats() DispatcherStats {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	stats := DispatcherStats{
+		Capacity:   d.queueSize * len(d.queues),
+		HighWater:  int(atomic.LoadInt64(&d.highWater)),
+		Dispatched: atomic.LoadUint64(&d.dispatched),
+		Dropped:    atomic.LoadUint64(&d.dropped),
+		Panics:     atomic.LoadUint64(&d.panics),
+	}
+	if d.running {
+		for _, queue := range d.queues {
+			stats.Queued += len(queue)
+		}
+	}
+	return stats
+}
+
+// DispatcherStats returns the statistics of the asynchronous event dispatch
+func (c *CCU) DispatcherStats() DispatcherStats {
+	return c.dispatcher.stats()
+}
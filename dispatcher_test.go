@@ -0,0 +1,159 @@
+package homematic
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDispatcher(t *testing.T) {
+	ass := assert.New(t)
+
+	d := newDispatcher(0, 0, nil)
+	ass.Len(d.queues, 1)
+	ass.Equal(1, d.queueSize)
+}
+
+func TestDispatcher_order(t *testing.T) {
+	ass := assert.New(t)
+
+	d := newDispatcher(4, 1000, nil)
+
+	var mutex sync.Mutex
+	values := make(map[string][]int)
+	for i := 0; i < 100; i++ {
+		for _, address := range []string{"a", "b", "c", "d", "e"} {
+			address, i := address, i
+			ass.True(d.dispatch(Event{Address: address}, func() {
+				mutex.Lock()
+				values[address] = append(values[address], i)
+				mutex.Unlock()
+			}))
+		}
+	}
+	d.stop()
+
+	for address, list := range values {
+		ass.Len(list, 100, address)
+		for i, value := range list {
+			ass.Equal(i, value, address)
+		}
+	}
+
+	stats := d.stats()
+	ass.Equal(uint64(500), stats.Dispatched)
+	ass.Equal(uint64(0), stats.Dropped)
+	ass.Equal(0, stats.Queued)
+	ass.Equal(4000, stats.Capacity)
+	ass.True(stats.HighWater > 0)
+}
+
+func TestDispatcher_panic(t *testing.T) {
+	ass := assert.New(t)
+
+	var recoveredEvent Event
+	var recoveredValue interface{}
+	d := newDispatcher(1, 10, func(event Event, recovered interface{}) {
+		recoveredEvent = event
+		recoveredValue = recovered
+	})
+
+	var called bool
+	d.dispatch(Event{Address: "a", Parameter: "STATE"}, func() {
+		panic("failure")
+	})
+	d.dispatch(Event{Address: "a"}, func() {
+		called = true
+	})
+	d.stop()
+
+	// next handler is called after panic
+	ass.True(called)
+	ass.Equal("STATE", recoveredEvent.Parameter)
+	ass.Equal("failure", recoveredValue)
+	ass.Equal(uint64(1), d.stats().Panics)
+	ass.Equal(uint64(2), d.stats().Dispatched)
+}
+
+func TestDispatcher_drop(t *testing.T) {
+	ass := assert.New(t)
+
+	d := newDispatcher(1, 2, nil)
+
+	// block worker
+	block := make(chan struct{})
+	started := make(chan struct{})
+	d.dispatch(Event{}, func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	ass.True(d.dispatch(Event{}, func() {}))
+	ass.True(d.dispatch(Event{}, func() {}))
+	ass.False(d.dispatch(Event{}, func() {}))
+
+	stats := d.stats()
+	ass.Equal(2, stats.Queued)
+	ass.Equal(2, stats.HighWater)
+	ass.Equal(uint64(1), stats.Dropped)
+
+	close(block)
+	d.stop()
+	ass.Equal(uint64(3), d.stats().Dispatched)
+
+	// events are dropped after stop
+	ass.False(d.dispatch(Event{}, func() {
+		ass.Fail("handler called after stop")
+	}))
+	ass.Equal(uint64(2), d.stats().Dropped)
+
+	// dispatcher is started again explicitly
+	d.start()
+	done := make(chan struct{})
+	ass.True(d.dispatch(Event{}, func() {
+		close(done)
+	}))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		ass.Fail("handler not called")
+	}
+	d.stop()
+}
+
+func TestCCU_DispatcherStats(t *testing.T) {
+	ass := assert.New(t)
+
+	var recovered interface{}
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithDispatcher(2, 10),
+		WithPanicHandler(func(event Event, r interface{}) {
+			recovered = fmt.Sprintf("%s %v", event.Address, r)
+		}))
+	ass.NoError(err)
+
+	device := &Device{
+		Address: "address",
+	}
+	device.SetValueChangedHandler(func(key string, value interface{}) {
+		panic("handler failed")
+	})
+	ccu.devices[device.Address] = device
+
+	_, fault := ccu.callbackEvent([]interface{}{
+		"id", "address", "STATE", true,
+	})
+	ass.Nil(fault)
+
+	ccu.dispatcher.stop()
+	ass.Equal("address handler failed", recovered)
+
+	stats := ccu.DispatcherStats()
+	ass.Equal(20, stats.Capacity)
+	ass.Equal(uint64(1), stats.Dispatched)
+	ass.Equal(uint64(1), stats.Panics)
+}
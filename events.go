@@ -0,0 +1,141 @@
+package homematic
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// size of the channel buffer of each event stream
+// -> events are dropped if the buffer of a stream is full
+const eventBufferSize = 100
+
+// Event received from an interface of the CCU
+type Event struct {
+	// Interface id the event was received from (e.g. "go-rf")
+	Interface string
+	// Address of the device or channel (e.g. "OEQ1234567:1")
+	Address string
+	// Parameter that changed (e.g. "STATE")
+	Parameter string
+	// Value of the parameter
+	Value interface{}
+	// ReceivedAt is the time the event was received
+	ReceivedAt time.Time
+	// Synthetic is true if the event was created by a snapshot or by
+	// polling (see WithSnapshot and WithPolling) and not received from the CCU
+	Synthetic bool
+}
+
+// EventFilter selects the events of an event stream
+// -> empty lists match all events
+type EventFilter struct {
+	// Addresses of devices or channels as pattern (e.g. "OEQ1234567:*")
+	// -> syntax of path.Match
+	Addresses []string
+	// Parameters names (e.g. "STATE" or "LEVEL")
+	Parameters []string
+	// DeviceTypes of the channel or its device (e.g. "SWITCH" or "HM-LC-Sw1-FM")
+	// -> events of unknown devices never match
+	DeviceTypes []string
+}
+
+// match returns true if the event of a device with the given types matches
+func (f EventFilter) match(event Event, deviceTypes []string) bool {
+	if len(f.Addresses) > 0 && !matchAny(f.Addresses, event.Address, matchPattern) {
+		return false
+	}
+	if len(f.Parameters) > 0 && !matchAny(f.Parameters, event.Parameter, matchEqual) {
+		return false
+	}
+	if len(f.DeviceTypes) > 0 {
+		for _, deviceType := range deviceTypes {
+			if matchAny(f.DeviceTypes, deviceType, matchEqual) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchAny returns true if any of the patterns matches the value
+func matchAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEqual returns true if value is equal to pattern
+func matchEqual(pattern, value string) bool {
+	return pattern == value
+}
+
+// matchPattern returns true if value matches the pattern
+// (invalid patterns never match)
+func matchPattern(pattern, value string) bool {
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// eventStream of a single Events call
+type eventStream struct {
+	filter EventFilter
+	events chan Event
+}
+
+// Events returns a stream of all events matching the filter
+// -> the channel is closed if the context is done
+// -> events are dropped if the channel is not read fast enough
+func (c *CCU) Events(ctx context.Context, filter EventFilter) <-chan Event {
+	stream := &eventStream{
+		filter: filter,
+		events: make(chan Event, eventBufferSize),
+	}
+
+	c.eventMutex.Lock()
+	c.eventStreams[stream] = true
+	c.eventMutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.eventMutex.Lock()
+		delete(c.eventStreams, stream)
+		close(stream.events)
+		c.eventMutex.Unlock()
+	}()
+	return stream.events
+}
+
+// publishEvent to all matching event streams
+func (c *CCU) publishEvent(event Event, deviceTypes []string) {
+	c.eventMutex.Lock()
+	defer c.eventMutex.Unlock()
+
+	for stream := range c.eventStreams {
+		if !stream.filter.match(event, deviceTypes) {
+			continue
+		}
+
+		// never block callback handling
+		select {
+		case stream.events <- event:
+		default:
+		}
+	}
+}
+
+// deviceTypes of the device and its parent
+// Note: deviceMutex must be held by caller
+func (c *CCU) deviceTypes(device *Device) []string {
+	types := []string{device.GetType()}
+	address := device.GetParent()
+	if parent, ok := c.devices[address]; ok && address != "" {
+		types = append(types, parent.GetType())
+	}
+	return types
+}
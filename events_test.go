@@ -0,0 +1,134 @@
+package homematic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+	"gitlab.com/bboehmke/homematic/script"
+)
+
+func TestEventFilter_match(t *testing.T) {
+	ass := assert.New(t)
+
+	event := Event{
+		Interface: "go-rf",
+		Address:   "OEQ1234567:1",
+		Parameter: "STATE",
+		Value:     true,
+	}
+	types := []string{"SWITCH", "HM-LC-Sw1-FM"}
+
+	ass.True(EventFilter{}.match(event, nil))
+	ass.True(EventFilter{Addresses: []string{"OEQ1234567:*"}}.match(event, types))
+	ass.True(EventFilter{Addresses: []string{"other", "OEQ1234567:1"}}.match(event, types))
+	ass.False(EventFilter{Addresses: []string{"OEQ1234567"}}.match(event, types))
+	ass.False(EventFilter{Addresses: []string{"[invalid"}}.match(event, types))
+
+	ass.True(EventFilter{Parameters: []string{"LEVEL", "STATE"}}.match(event, types))
+	ass.False(EventFilter{Parameters: []string{"LEVEL"}}.match(event, types))
+
+	ass.True(EventFilter{DeviceTypes: []string{"SWITCH"}}.match(event, types))
+	ass.True(EventFilter{DeviceTypes: []string{"HM-LC-Sw1-FM"}}.match(event, types))
+	ass.False(EventFilter{DeviceTypes: []string{"DIMMER"}}.match(event, types))
+	ass.False(EventFilter{DeviceTypes: []string{"SWITCH"}}.match(event, nil))
+
+	ass.True(EventFilter{
+		Addresses:   []string{"OEQ*"},
+		Parameters:  []string{"STATE"},
+		DeviceTypes: []string{"SWITCH"},
+	}.match(event, types))
+	ass.False(EventFilter{
+		Addresses:  []string{"OEQ*"},
+		Parameters: []string{"LEVEL"},
+	}.match(event, types))
+}
+
+func TestCCU_Events(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+	ccu.rpcClients = map[string]rpc.Client{}
+	ccu.scriptClient = testScriptClient(func(script string) (script.Result, error) {
+		return map[string]string{}, nil
+	})
+	ccu.devices["OEQ1234567"] = &Device{
+		Address: "OEQ1234567",
+		Type:    "HM-LC-Sw1-FM",
+	}
+	ccu.devices["OEQ1234567:1"] = &Device{
+		Address: "OEQ1234567:1",
+		Parent:  "OEQ1234567",
+		Type:    "SWITCH",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	all := ccu.Events(ctx, EventFilter{})
+	switches := ccu.Events(ctx, EventFilter{
+		DeviceTypes: []string{"HM-LC-Sw1-FM"},
+		Parameters:  []string{"STATE"},
+	})
+
+	before := time.Now()
+	_, fault := ccu.callbackEvent([]interface{}{
+		"go-rf", "OEQ1234567:1", "STATE", true,
+	})
+	ass.Nil(fault)
+	_, fault = ccu.callbackEvent([]interface{}{
+		"go-rf", "unknown", "STATE", false,
+	})
+	ass.Nil(fault)
+
+	event := <-all
+	ass.Equal("go-rf", event.Interface)
+	ass.Equal("OEQ1234567:1", event.Address)
+	ass.Equal("STATE", event.Parameter)
+	ass.Equal(true, event.Value)
+	ass.False(event.ReceivedAt.Before(before))
+
+	// events of unknown devices
+	event = <-all
+	ass.Equal("unknown", event.Address)
+	ass.Equal(false, event.Value)
+
+	event = <-switches
+	ass.Equal("OEQ1234567:1", event.Address)
+	select {
+	case event = <-switches:
+		ass.Fail("unexpected event", event)
+	default:
+	}
+
+	// channels are closed if context is done
+	cancel()
+	_, ok := <-all
+	ass.False(ok)
+	_, ok = <-switches
+	ass.False(ok)
+
+	ccu.eventMutex.Lock()
+	ass.Empty(ccu.eventStreams)
+	ccu.eventMutex.Unlock()
+}
+
+func TestCCU_Events_full(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := ccu.Events(ctx, EventFilter{})
+
+	// full stream does not block
+	for i := 0; i < eventBufferSize+10; i++ {
+		ccu.publishEvent(Event{Value: i}, nil)
+	}
+	ass.Len(events, eventBufferSize)
+	ass.Equal(0, (<-events).Value)
+}
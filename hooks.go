@@ -0,0 +1,203 @@
+package homematic
+
+import (
+	"sync"
+)
+
+// deviceHooks called on changes of the device list
+type deviceHooks struct {
+	added    func(device *Device)
+	removed  func(device *Device)
+	renamed  func(device *Device, oldName string)
+	replaced func(oldDevice, newDevice *Device)
+	updated  func(device *Device)
+}
+
+// OnDeviceAdded sets the handler called if a device or channel is added
+// -> also called for all devices on the first device list update
+func (c *CCU) OnDeviceAdded(handler func(device *Device)) {
+	c.hookMutex.Lock()
+	defer c.hookMutex.Unlock()
+
+	c.hooks.added = handler
+}
+
+// OnDeviceRemoved sets the handler called if a device or channel is removed
+func (c *CCU) OnDeviceRemoved(handler func(device *Device)) {
+	c.hookMutex.Lock()
+	defer c.hookMutex.Unlock()
+
+	c.hooks.removed = handler
+}
+
+// OnDeviceRenamed sets the handler called if the name of a device or
+// channel changed in the logic layer
+func (c *CCU) OnDeviceRenamed(handler func(device *Device, oldName string)) {
+	c.hookMutex.Lock()
+	defer c.hookMutex.Unlock()
+
+	c.hooks.renamed = handler
+}
+
+// OnDeviceReplaced sets the handler called if a device was replaced by
+// another one (new device and channels are also reported as added and the
+// channels of the old device as removed)
+func (c *CCU) OnDeviceReplaced(handler func(oldDevice, newDevice *Device)) {
+	c.hookMutex.Lock()
+	defer c.hookMutex.Unlock()
+
+	c.hooks.replaced = handler
+}
+
+// OnDeviceUpdated sets the handler called if the description of a device
+// or channel changed (e.g. after a firmware update)
+func (c *CCU) OnDeviceUpdated(handler func(device *Device)) {
+	c.hookMutex.Lock()
+	defer c.hookMutex.Unlock()
+
+	c.hooks.updated = handler
+}
+
+// getHooks returns a copy of the current hooks
+func (c *CCU) getHooks() deviceHooks {
+	c.hookMutex.RLock()
+	defer c.hookMutex.RUnlock()
+
+	return c.hooks
+}
+
+// deviceAdded calls the added hooks of the devices
+func (c *CCU) deviceAdded(devices ...*Device) {
+	hook := c.getHooks().added
+	if hook == nil {
+		return
+	}
+	for _, device := range devices {
+		device := device
+		c.hookQueue.push(Event{Address: device.Address}, func() {
+			hook(device)
+		})
+	}
+}
+
+// deviceRemoved calls the removed hooks of the devices
+func (c *CCU) deviceRemoved(devices ...*Device) {
+	hook := c.getHooks().removed
+	if hook == nil {
+		return
+	}
+	for _, device := range devices {
+		device := device
+		c.hookQueue.push(Event{Address: device.Address}, func() {
+			hook(device)
+		})
+	}
+}
+
+// deviceRenamed calls the renamed hook of the device
+func (c *CCU) deviceRenamed(device *Device, oldName string) {
+	hook := c.getHooks().renamed
+	if hook == nil {
+		return
+	}
+	c.hookQueue.push(Event{Address: device.Address}, func() {
+		hook(device, oldName)
+	})
+}
+
+// deviceReplaced calls the replaced hook of the devices
+func (c *CCU) deviceReplaced(oldDevice, newDevice *Device) {
+	hook := c.getHooks().replaced
+	if hook == nil {
+		return
+	}
+	c.hookQueue.push(Event{Address: newDevice.Address}, func() {
+		hook(oldDevice, newDevice)
+	})
+}
+
+// deviceUpdated calls the updated hooks of the devices
+func (c *CCU) deviceUpdated(devices ...*Device) {
+	hook := c.getHooks().updated
+	if hook == nil {
+		return
+	}
+	for _, device := range devices {
+		device := device
+		c.hookQueue.push(Event{Address: device.Address}, func() {
+			hook(device)
+		})
+	}
+}
+
+// hookQueue calls the hooks in order in a separate goroutine
+// -> hooks are never dropped, the queue grows if hooks are slow
+type hookQueue struct {
+	panicHandler func(event Event, recovered interface{})
+
+	tasks   []dispatchTask
+	running bool
+	idle    *sync.Cond
+	mutex   sync.Mutex
+}
+
+// newHookQueue with the handler called on panics in hooks
+func newHookQueue(panicHandler func(event Event, recovered interface{})) *hookQueue {
+	q := &hookQueue{
+		panicHandler: panicHandler,
+	}
+	q.idle = sync.NewCond(&q.mutex)
+	return q
+}
+
+// push the hook to the queue
+// -> never blocks, can be called with device list locked
+func (q *hookQueue) push(event Event, handler func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.tasks = append(q.tasks, dispatchTask{event: event, handler: handler})
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+// run queued hooks until the queue is empty
+func (q *hookQueue) run() {
+	for {
+		q.mutex.Lock()
+		if len(q.tasks) == 0 {
+			q.running = false
+			q.idle.Broadcast()
+			q.mutex.Unlock()
+			return
+		}
+		task := q.tasks[0]
+		q.tasks[0] = dispatchTask{}
+		q.tasks = q.tasks[1:]
+		q.mutex.Unlock()
+
+		q.handle(task)
+	}
+}
+
+// handle hook and recover from panics
+func (q *hookQueue) handle(task dispatchTask) {
+	defer func() {
+		if recovered := recover(); recovered != nil && q.panicHandler != nil {
+			q.panicHandler(task.event, recovered)
+		}
+	}()
+	task.handler()
+}
+
+// wait until all queued hooks are called
+func (q *hookQueue) wait() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for q.running {
+		q.idle.Wait()
+	}
+}
@@ -0,0 +1,290 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+	"gitlab.com/bboehmke/homematic/script"
+)
+
+// testHooks records all calls of the device hooks
+type testHooks struct {
+	added    []string
+	removed  []string
+	renamed  []string
+	replaced []string
+	updated  []string
+	mutex    sync.Mutex
+}
+
+func (h *testHooks) register(ccu *CCU) {
+	record := func(list *[]string, value string) {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		*list = append(*list, value)
+		sort.Strings(*list)
+	}
+
+	ccu.OnDeviceAdded(func(device *Device) {
+		record(&h.added, device.Address)
+	})
+	ccu.OnDeviceRemoved(func(device *Device) {
+		record(&h.removed, device.Address)
+	})
+	ccu.OnDeviceRenamed(func(device *Device, oldName string) {
+		record(&h.renamed, oldName+"->"+device.GetName())
+	})
+	ccu.OnDeviceReplaced(func(oldDevice, newDevice *Device) {
+		record(&h.replaced, oldDevice.Address+"->"+newDevice.Address)
+	})
+	ccu.OnDeviceUpdated(func(device *Device) {
+		record(&h.updated, device.Address)
+	})
+}
+
+// testDescriptionClient returns the device descriptions
+func testDescriptionClient(descriptions map[string]map[string]interface{}) testRpcClient {
+	return func(method string, params []interface{}) (*rpc.Response, error) {
+		if method != "getDeviceDescription" {
+			return nil, errors.New("unexpected method")
+		}
+		description, ok := descriptions[params[0].(string)]
+		if !ok {
+			return &rpc.Response{
+				Fault: &rpc.Fault{Code: -2, String: "Unknown instance"},
+			}, nil
+		}
+		return &rpc.Response{
+			Params: []interface{}{description},
+		}, nil
+	}
+}
+
+func testHookCCU(ass *assert.Assertions, names map[string]string) (*CCU, *testHooks) {
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	ccu.scriptClient = testScriptClient(func(script string) (script.Result, error) {
+		output := ""
+		for address, name := range names {
+			output += address + "=" + name + "\n"
+		}
+		return map[string]string{"output": output}, nil
+	})
+
+	hooks := new(testHooks)
+	hooks.register(ccu)
+	return ccu, hooks
+}
+
+func TestCCU_hooks_UpdateDevices(t *testing.T) {
+	ass := assert.New(t)
+
+	names := map[string]string{"a": "device a", "b": "device b"}
+	ccu, hooks := testHookCCU(ass, names)
+
+	addresses := []string{"a", "b"}
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			descriptions := make([]interface{}, len(addresses))
+			for i, address := range addresses {
+				descriptions[i] = map[string]interface{}{"ADDRESS": address}
+			}
+			return &rpc.Response{
+				Params: []interface{}{descriptions},
+			}, nil
+		}),
+	}
+
+	ass.NoError(ccu.UpdateDevices(true))
+
+	names["a"] = "renamed a"
+	addresses = []string{"a"}
+	ass.NoError(ccu.UpdateDevices(true))
+	ccu.hookQueue.wait()
+
+	ass.Equal([]string{"a", "b"}, hooks.added)
+	ass.Equal([]string{"b"}, hooks.removed)
+	ass.Equal([]string{"device a->renamed a"}, hooks.renamed)
+}
+
+func TestCCU_callbackDeleteDevices(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, hooks := testHookCCU(ass, nil)
+	ccu.devices["a"] = &Device{Address: "a"}
+	ccu.devices["a:1"] = &Device{Address: "a:1"}
+	ccu.devices["b"] = &Device{Address: "b"}
+
+	resp, fault := ccu.handleCallback("deleteDevices", []interface{}{
+		"test", []interface{}{"a", "a:1", "unknown"},
+	})
+	ass.Nil(fault)
+	ass.Equal([]interface{}{true}, resp)
+	ass.Len(ccu.devices, 1)
+	ass.Contains(ccu.devices, "b")
+
+	_, fault = ccu.handleCallback("deleteDevices", []interface{}{"test"})
+	ass.Equal(&rpc.Fault{Code: -1, String: "invalid deleteDevices call"}, fault)
+
+	ccu.hookQueue.wait()
+	ass.Equal([]string{"a", "a:1"}, hooks.removed)
+}
+
+func TestCCU_callbackUpdateDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, hooks := testHookCCU(ass, map[string]string{"a:2": "new channel"})
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testDescriptionClient(map[string]map[string]interface{}{
+			"a": {
+				"ADDRESS":  "a",
+				"VERSION":  2,
+				"CHILDREN": []interface{}{"a:1", "a:2"},
+			},
+			"a:1": {"ADDRESS": "a:1", "PARENT": "a", "VERSION": 2},
+			"a:2": {"ADDRESS": "a:2", "PARENT": "a", "VERSION": 2},
+		}),
+	}
+	device := &Device{Address: "a", Version: 1}
+	ccu.devices["a"] = device
+	ccu.devices["a:1"] = &Device{Address: "a:1", Version: 1}
+
+	resp, fault := ccu.handleCallback("updateDevice", []interface{}{
+		"test", "a", 0,
+	})
+	ass.Nil(fault)
+	ass.Equal([]interface{}{true}, resp)
+
+	// device is updated in background
+	ass.Len(ccu.devices, 2)
+	ccu.runDeviceUpdates(context.Background())
+	ass.Len(ccu.devices, 3)
+	ass.True(device == ccu.devices["a"])
+	ass.Equal(2, device.Version)
+	ass.Equal([]string{"a:1", "a:2"}, device.Children)
+	ass.Equal("new channel", ccu.devices["a:2"].Name)
+	ass.Equal("test", ccu.devices["a:2"].Interface)
+
+	// unknown devices are ignored
+	_, fault = ccu.handleCallback("updateDevice", []interface{}{
+		"test", "unknown", 0,
+	})
+	ass.Nil(fault)
+	ccu.runDeviceUpdates(context.Background())
+
+	_, fault = ccu.handleCallback("updateDevice", []interface{}{
+		"invalid", "a", 0,
+	})
+	ass.Equal(&rpc.Fault{Code: -1, String: "invalid interface id"}, fault)
+	_, fault = ccu.handleCallback("updateDevice", []interface{}{"test"})
+	ass.Equal(&rpc.Fault{Code: -1, String: "invalid updateDevice call"}, fault)
+
+	ccu.hookQueue.wait()
+	ass.Equal([]string{"a", "a:1"}, hooks.updated)
+	ass.Equal([]string{"a:2"}, hooks.added)
+}
+
+func TestCCU_callbackReplaceDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, hooks := testHookCCU(ass, map[string]string{"b": "new device"})
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testDescriptionClient(map[string]map[string]interface{}{
+			"b": {
+				"ADDRESS":  "b",
+				"CHILDREN": []interface{}{"b:1"},
+			},
+			"b:1": {"ADDRESS": "b:1", "PARENT": "b"},
+		}),
+	}
+	ccu.devices["a"] = &Device{Address: "a", Children: []string{"a:1"}}
+	ccu.devices["a:1"] = &Device{Address: "a:1"}
+
+	resp, fault := ccu.handleCallback("replaceDevice", []interface{}{
+		"test", "a", "b",
+	})
+	ass.Nil(fault)
+	ass.Equal([]interface{}{true}, resp)
+
+	// device is replaced in background
+	ass.Contains(ccu.devices, "a")
+	ccu.runDeviceUpdates(context.Background())
+	ass.Len(ccu.devices, 2)
+	ass.Contains(ccu.devices, "b")
+	ass.Contains(ccu.devices, "b:1")
+	ass.Equal("new device", ccu.devices["b"].Name)
+
+	_, fault = ccu.handleCallback("replaceDevice", []interface{}{"test", "a"})
+	ass.Equal(&rpc.Fault{Code: -1, String: "invalid replaceDevice call"}, fault)
+
+	ccu.hookQueue.wait()
+	ass.Equal([]string{"a->b"}, hooks.replaced)
+	ass.Equal([]string{"a", "a:1"}, hooks.removed)
+	ass.Equal([]string{"b", "b:1"}, hooks.added)
+}
+
+func TestCCU_callbackReaddedDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, hooks := testHookCCU(ass, nil)
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testDescriptionClient(map[string]map[string]interface{}{
+			"a":   {"ADDRESS": "a", "TYPE": "new"},
+			"a:1": {"ADDRESS": "a:1", "PARENT": "a"},
+		}),
+	}
+	ccu.devices["a"] = &Device{Address: "a", Type: "old"}
+
+	resp, fault := ccu.handleCallback("readdedDevice", []interface{}{
+		"test", []interface{}{"a", "a:1", "unknown"},
+	})
+	ass.Nil(fault)
+	ass.Equal([]interface{}{true}, resp)
+
+	// devices are updated in background
+	ass.Equal("old", ccu.devices["a"].Type)
+	ccu.runDeviceUpdates(context.Background())
+	ass.Len(ccu.devices, 2)
+	ass.Equal("new", ccu.devices["a"].Type)
+
+	_, fault = ccu.handleCallback("readdedDevice", []interface{}{"test", "a"})
+	ass.Equal(&rpc.Fault{Code: -1, String: "invalid readdedDevice call"}, fault)
+
+	ccu.hookQueue.wait()
+	ass.Equal([]string{"a"}, hooks.updated)
+	ass.Equal([]string{"a:1"}, hooks.added)
+}
+
+func TestCCU_hooks_noDrop(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1", WithDispatcher(1, 1))
+	ass.NoError(err)
+
+	block := make(chan struct{})
+	var added []string
+	ccu.OnDeviceAdded(func(device *Device) {
+		<-block
+		added = append(added, device.Address)
+	})
+
+	// more hooks than the dispatcher queue could hold
+	var expected []string
+	for i := 0; i < 20; i++ {
+		device := &Device{Address: fmt.Sprintf("a:%02d", i)}
+		ccu.deviceAdded(device)
+		expected = append(expected, device.Address)
+	}
+	close(block)
+
+	ccu.hookQueue.wait()
+	ass.Equal(expected, added)
+}
@@ -0,0 +1,195 @@
+package homematic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// Interface process of the CCU
+type Interface struct {
+	// Name used as suffix of the interface id (e.g. "go-rf")
+	Name string
+	// Port of the interface process
+	Port int
+	// TLSPort of the interface process (used with WithTLS)
+	// -> 0 if interface is only available without TLS
+	TLSPort int
+	// Path of the RPC endpoint (e.g. "/groups")
+	Path string
+	// Binary is true if the interface uses the binary RPC protocol
+	Binary bool
+}
+
+// interface processes available on each CCU
+var (
+	// InterfaceWired for HomeMatic Wired devices
+	InterfaceWired = Interface{Name: "wired", Port: 2000, TLSPort: 42000}
+	// InterfaceRF for HomeMatic (BidCos-RF) devices
+	InterfaceRF = Interface{Name: "rf", Port: 2001, TLSPort: 42001}
+	// InterfaceHmIP for HomeMatic IP devices
+	InterfaceHmIP = Interface{Name: "hmip", Port: 2010, TLSPort: 42010}
+)
+
+// optional interface processes of addons and virtual devices
+var (
+	// InterfaceCUxD for devices of the CUxD addon (binary RPC only)
+	InterfaceCUxD = Interface{Name: "cuxd", Port: 8701, Binary: true}
+	// InterfaceVirtualDevices for virtual devices (e.g. heating groups)
+	InterfaceVirtualDevices = Interface{Name: "groups", Port: 9292, TLSPort: 49292, Path: "/groups"}
+)
+
+// InterfaceStatus of an interface process on the CCU
+type InterfaceStatus struct {
+	Interface Interface
+	// Available is true if the interface process responded
+	Available bool
+	// Version reported by the interface process (empty if not supported)
+	Version string
+	// Error of the last failed request to the interface process
+	Error error
+	// LastCheck of the availability
+	LastCheck time.Time
+
+	// State of the callback connection checked by ping
+	State ConnectionState
+	// LastPong is the time of the last answered ping
+	LastPong time.Time
+	// Polling is true if the values of the devices are polled
+	// (see WithPolling)
+	Polling bool
+}
+
+// probeInterface checks if the interface process is reachable
+func probeInterface(ctx context.Context, client rpc.Client) InterfaceStatus {
+	status := InterfaceStatus{
+		LastCheck: time.Now(),
+	}
+
+	response, err := client.CallContext(ctx, "system.listMethods", nil)
+	if err != nil {
+		status.Error = err
+		return status
+	}
+	if response.Fault != nil {
+		status.Error = response.Fault
+		return status
+	}
+	status.Available = true
+
+	// version is optional -> not supported by all interfaces
+	var methods []string
+	_ = rpc.Unmarshal(response.FirstParam(), &methods)
+	for _, method := range methods {
+		if method != "getVersion" {
+			continue
+		}
+		response, err = client.CallContext(ctx, "getVersion", nil)
+		if err == nil && response.Fault == nil {
+			status.Version = cast.ToString(response.FirstParam())
+		}
+	}
+	return status
+}
+
+// Interfaces returns the status of all interface processes by interface id
+// (empty until the interfaces are discovered by Start, UpdateDevices or
+// DiscoverInterfaces)
+func (c *CCU) Interfaces() map[string]InterfaceStatus {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	interfaces := make(map[string]InterfaceStatus, len(c.interfaceStatus))
+	for id, status := range c.interfaceStatus {
+		interfaces[id] = status
+	}
+	return interfaces
+}
+
+// DiscoverInterfaces checks which interface processes are available
+func (c *CCU) DiscoverInterfaces() map[string]InterfaceStatus {
+	return c.DiscoverInterfacesContext(context.Background())
+}
+
+// DiscoverInterfacesContext checks which interface processes are available
+// and aborts if the context is done
+func (c *CCU) DiscoverInterfacesContext(ctx context.Context) map[string]InterfaceStatus {
+	c.discoverInterfaces(ctx, true)
+	return c.Interfaces()
+}
+
+// discoverInterfaces probes all or only unavailable interface processes
+// -> probed without lock to not block callbacks while waiting for
+// dead interfaces
+func (c *CCU) discoverInterfaces(ctx context.Context, all bool) {
+	c.clientMutex.RLock()
+	clients := make(map[string]rpc.Client, len(c.rpcClients))
+	for id, client := range c.rpcClients {
+		if all || !c.interfaceStatus[id].Available {
+			clients[id] = client
+		}
+	}
+	c.clientMutex.RUnlock()
+
+	// probe interfaces in parallel -> dead interfaces block until timeout
+	var wg sync.WaitGroup
+	results := make(map[string]InterfaceStatus, len(clients))
+	var mutex sync.Mutex
+	for id, client := range clients {
+		wg.Add(1)
+		go func(id string, client rpc.Client) {
+			defer wg.Done()
+			status := probeInterface(ctx, client)
+
+			mutex.Lock()
+			results[id] = status
+			mutex.Unlock()
+		}(id, client)
+	}
+	wg.Wait()
+
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	for id, status := range results {
+		// keep state of callback connection
+		previous := c.interfaceStatus[id]
+		status.State = previous.State
+		status.LastPong = previous.LastPong
+		status.Polling = previous.Polling
+		if !status.Available {
+			status.State = ConnectionUnreachable
+		}
+
+		status.Interface = c.interfaces[id]
+		c.interfaceStatus[id] = status
+	}
+}
+
+// interfaceFailed marks the interface as unavailable
+// Note: clientMutex must be held by caller
+func (c *CCU) interfaceFailed(id string, err error) {
+	status := c.interfaceStatus[id]
+	status.Interface = c.interfaces[id]
+	status.Available = false
+	status.State = ConnectionUnreachable
+	status.Error = err
+	status.LastCheck = time.Now()
+	c.interfaceStatus[id] = status
+}
+
+// availableClients returns the clients of available interfaces
+// Note: clientMutex must be held by caller
+func (c *CCU) availableClients() map[string]rpc.Client {
+	clients := make(map[string]rpc.Client, len(c.rpcClients))
+	for id, client := range c.rpcClients {
+		if c.interfaceStatus[id].Available {
+			clients[id] = client
+		}
+	}
+	return clients
+}
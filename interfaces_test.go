@@ -0,0 +1,218 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+	"gitlab.com/bboehmke/homematic/script"
+)
+
+func TestCCU_binaryInterface(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceCUxD),
+		WithCallbackListenAddress("127.0.0.1:0"))
+	ass.NoError(err)
+
+	// get callback URL from init call
+	urls := make(chan string, 2)
+	var client testRpcClient = func(method string, params []interface{}) (*rpc.Response, error) {
+		if method == "ping" {
+			go ccu.handleCallback("event", []interface{}{
+				"go-cuxd", "CENTRAL", "PONG", params[0],
+			})
+			return &rpc.Response{}, nil
+		}
+		ass.Equal("init", method)
+		urls <- params[0].(string)
+		return &rpc.Response{}, nil
+	}
+	ccu.rpcClients["go-cuxd"] = client
+
+	values := make(chan interface{}, 1)
+	device := &Device{
+		Address: "CUX0100001:1",
+	}
+	device.SetValueChangedHandler(func(key string, value interface{}) {
+		values <- value
+	})
+	ccu.devices[device.Address] = device
+
+	ass.NoError(ccu.Start())
+	url := <-urls
+	ass.Regexp("^xmlrpc_bin://127.0.0.1:[0-9]+$", url)
+
+	// send event to binary callback server
+	_, err = rpc.NewBinClient(url).Call("event", []interface{}{
+		"go-cuxd", "CUX0100001:1", "STATE", true,
+	})
+	ass.NoError(err)
+
+	select {
+	case value := <-values:
+		ass.Equal(true, value)
+	case <-time.After(time.Second):
+		ass.Fail("event not received")
+	}
+
+	ass.NoError(ccu.Stop())
+	ass.Equal(url, <-urls)
+}
+
+// testInterfaceClient passes all calls including the interface discovery
+type testInterfaceClient func(method string, params []interface{}) (*rpc.Response, error)
+
+func (c testInterfaceClient) Call(method string, params []interface{}) (*rpc.Response, error) {
+	return c(method, params)
+}
+
+func (c testInterfaceClient) CallContext(_ context.Context, method string, params []interface{}) (*rpc.Response, error) {
+	return c(method, params)
+}
+
+func (c testInterfaceClient) LocalIP() (string, error) {
+	return "127.0.0.1", nil
+}
+
+func TestProbeInterface(t *testing.T) {
+	ass := assert.New(t)
+
+	status := probeInterface(context.Background(), testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		switch method {
+		case "system.listMethods":
+			return &rpc.Response{
+				Params: []interface{}{[]interface{}{"getVersion", "init"}},
+			}, nil
+		case "getVersion":
+			return &rpc.Response{
+				Params: []interface{}{"1.2.3"},
+			}, nil
+		}
+		return nil, errors.New("unexpected call")
+	}))
+	ass.True(status.Available)
+	ass.Equal("1.2.3", status.Version)
+	ass.NoError(status.Error)
+
+	// getVersion not supported
+	status = probeInterface(context.Background(), testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("system.listMethods", method)
+		return &rpc.Response{
+			Params: []interface{}{[]interface{}{"init"}},
+		}, nil
+	}))
+	ass.True(status.Available)
+	ass.Equal("", status.Version)
+
+	status = probeInterface(context.Background(), testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		return &rpc.Response{
+			Fault: &rpc.Fault{Code: -1, String: "failure"},
+		}, nil
+	}))
+	ass.False(status.Available)
+	ass.EqualError(status.Error, "failure (-1)")
+
+	status = probeInterface(context.Background(), testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	ass.False(status.Available)
+	ass.EqualError(status.Error, "connection refused")
+}
+
+func TestCCU_DiscoverInterfaces(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceRF, InterfaceHmIP))
+	ass.NoError(err)
+	ass.Empty(ccu.Interfaces())
+
+	var hmipCalls int
+	ccu.rpcClients = map[string]rpc.Client{
+		"go-rf": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			return &rpc.Response{
+				Params: []interface{}{
+					[]interface{}{
+						map[string]interface{}{
+							"ADDRESS": "rf-address",
+						},
+					},
+				},
+			}, nil
+		}),
+		"go-hmip": testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			hmipCalls++
+			return nil, errors.New("connection refused")
+		}),
+	}
+	ccu.scriptClient = testScriptClient(func(script string) (script.Result, error) {
+		return map[string]string{}, nil
+	})
+
+	interfaces := ccu.DiscoverInterfaces()
+	ass.Len(interfaces, 2)
+	ass.True(interfaces["go-rf"].Available)
+	ass.Equal(InterfaceRF, interfaces["go-rf"].Interface)
+	ass.False(interfaces["go-hmip"].Available)
+	ass.Equal(InterfaceHmIP, interfaces["go-hmip"].Interface)
+	ass.EqualError(interfaces["go-hmip"].Error, "connection refused")
+	ass.Equal(1, hmipCalls)
+
+	// dead interface does not break the others and is checked again
+	devices, err := ccu.GetDevices()
+	ass.NoError(err)
+	ass.Len(devices, 1)
+	ass.Equal("go-rf", devices["rf-address"].Interface)
+	ass.Equal(2, hmipCalls)
+
+	// devices of failed interfaces are kept
+	ccu.rpcClients["go-rf"] = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		return nil, errors.New("timeout")
+	})
+	ass.EqualError(ccu.UpdateDevices(true), "timeout")
+	ass.False(ccu.Interfaces()["go-rf"].Available)
+	ass.Len(ccu.devices, 1)
+
+	// no interface available
+	ccu.rpcClients["go-rf"] = testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	ass.EqualError(ccu.UpdateDevices(true), "no interface available")
+}
+
+func TestCCU_discoverInterfaces_noLock(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1", WithInterfaces(InterfaceRF))
+	ass.NoError(err)
+
+	probing := make(chan struct{})
+	blocked := make(chan struct{})
+	ccu.rpcClients["go-rf"] = testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		close(probing)
+		<-blocked
+		return nil, errors.New("timeout")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ccu.DiscoverInterfaces()
+	}()
+	<-probing
+
+	// callbacks are not blocked by a dead interface
+	_, fault := ccu.callbackClient("go-rf")
+	ass.Nil(fault)
+	ass.Empty(ccu.Interfaces())
+
+	close(blocked)
+	<-done
+	ass.False(ccu.Interfaces()["go-rf"].Available)
+}
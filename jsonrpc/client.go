@@ -0,0 +1,236 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client interface for the JSON RPC API of the CCU WebUI
+type Client interface {
+	Call(method string, params map[string]interface{}, result interface{}) error
+	CallContext(ctx context.Context, method string, params map[string]interface{}, result interface{}) error
+	Login() error
+	Logout() error
+
+	InterfaceListDevices(iface string) ([]DeviceDescription, error)
+	DeviceListAllDetail() ([]Device, error)
+	RoomGetAll() ([]Room, error)
+	SysVarGetAll() ([]SysVar, error)
+	ReGaRunScript(script string) (string, error)
+}
+
+// NewClient creates new client
+// (e.g. http://192.168.4.40/api/homematic.cgi)
+func NewClient(url, username, password string) Client {
+	return &client{
+		URL:           url,
+		username:      username,
+		password:      password,
+		client:        new(http.Client),
+		timeout:       time.Second * 5,
+		renewInterval: time.Minute * 5,
+	}
+}
+
+// JSON RPC client
+type client struct {
+	URL      string
+	username string
+	password string
+	client   *http.Client
+	timeout  time.Duration
+
+	sessionID     string
+	lastActivity  time.Time
+	renewInterval time.Duration
+	requestID     int
+	mutex         sync.Mutex
+}
+
+// Error returned by the JSON RPC API
+type Error struct {
+	Name    string `json:"name"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// isSessionError returns true if the session is invalid or expired
+func (e *Error) isSessionError() bool {
+	return strings.Contains(strings.ToLower(e.Message), "access denied")
+}
+
+type request struct {
+	Version string                 `json:"version"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params"`
+	ID      int                    `json:"id"`
+}
+
+type response struct {
+	Version string          `json:"version"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+// Call method with parameters in current session and decode result
+// with the default timeout
+func (c *client) Call(method string, params map[string]interface{}, result interface{}) error {
+	return c.CallContext(context.Background(), method, params, result)
+}
+
+// CallContext calls method with parameters in current session and decode
+// result and aborts if the context is done
+// (default timeout is used if context has no deadline)
+func (c *client) CallContext(ctx context.Context, method string, params map[string]interface{}, result interface{}) error {
+	// use default timeout if context has no deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.ensureSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.call(ctx, method, c.sessionParams(params), result)
+	if e, ok := err.(*Error); ok && e.isSessionError() {
+		// session expired -> login again and retry
+		c.sessionID = ""
+		err = c.login(ctx)
+		if err != nil {
+			return err
+		}
+		err = c.call(ctx, method, c.sessionParams(params), result)
+	}
+	return err
+}
+
+// Login creates a new session
+func (c *client) Login() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.login(ctx)
+}
+
+// Logout closes the current session
+func (c *client) Logout() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	err := c.call(ctx, "Session.logout", c.sessionParams(nil), nil)
+	c.sessionID = ""
+	return err
+}
+
+// login creates a new session
+func (c *client) login(ctx context.Context) error {
+	var sessionID string
+	err := c.call(ctx, "Session.login", map[string]interface{}{
+		"username": c.username,
+		"password": c.password,
+	}, &sessionID)
+	if err != nil {
+		return err
+	}
+	c.sessionID = sessionID
+	return nil
+}
+
+// ensureSession creates a new session or renews the existing one
+func (c *client) ensureSession(ctx context.Context) error {
+	if c.sessionID == "" {
+		return c.login(ctx)
+	}
+
+	// renew session if not used since some time
+	if time.Since(c.lastActivity) < c.renewInterval {
+		return nil
+	}
+
+	var renewed bool
+	err := c.call(ctx, "Session.renew", c.sessionParams(nil), &renewed)
+	if err != nil || !renewed {
+		return c.login(ctx)
+	}
+	return nil
+}
+
+// sessionParams returns parameters extended with the session id
+func (c *client) sessionParams(params map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(params)+1)
+	for key, value := range params {
+		data[key] = value
+	}
+	data["_session_id_"] = c.sessionID
+	return data
+}
+
+// call sends a request to the API and decodes the result
+func (c *client) call(ctx context.Context, method string, params map[string]interface{}, result interface{}) error {
+	c.requestID++
+	data, err := json.Marshal(request{
+		Version: "1.1",
+		Method:  method,
+		Params:  params,
+		ID:      c.requestID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid response status %s", resp.Status)
+	}
+
+	var rpcResponse response
+	err = json.NewDecoder(resp.Body).Decode(&rpcResponse)
+	if err != nil {
+		return err
+	}
+	if rpcResponse.Error != nil {
+		return rpcResponse.Error
+	}
+	c.lastActivity = time.Now()
+
+	if result == nil || len(rpcResponse.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rpcResponse.Result, result)
+}
@@ -0,0 +1,161 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMethod func(params map[string]interface{}) (interface{}, *Error)
+
+// testServer emulates the JSON RPC API of the CCU
+func testServer(ass *assert.Assertions, methods map[string]testMethod) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+
+		var rpcRequest request
+		ass.NoError(json.NewDecoder(req.Body).Decode(&rpcRequest))
+		ass.Equal("1.1", rpcRequest.Version)
+
+		resp := map[string]interface{}{
+			"version": "1.1",
+			"result":  nil,
+			"error":   nil,
+		}
+		method, ok := methods[rpcRequest.Method]
+		if !ok {
+			resp["error"] = &Error{
+				Name:    "JSONRPCError",
+				Code:    501,
+				Message: "method not found",
+			}
+		} else {
+			result, err := method(rpcRequest.Params)
+			resp["result"] = result
+			if err != nil {
+				resp["error"] = err
+			}
+		}
+		ass.NoError(json.NewEncoder(rw).Encode(resp))
+	}))
+}
+
+// testSessionMethods returns login and logout handler
+func testSessionMethods(ass *assert.Assertions, methods map[string]testMethod) map[string]testMethod {
+	methods["Session.login"] = func(params map[string]interface{}) (interface{}, *Error) {
+		ass.Equal("user", params["username"])
+		ass.Equal("pass", params["password"])
+		return "session", nil
+	}
+	methods["Session.logout"] = func(params map[string]interface{}) (interface{}, *Error) {
+		ass.Equal("session", params["_session_id_"])
+		return true, nil
+	}
+	return methods
+}
+
+func TestClient_Call(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls []string
+	expired := true
+	server := testServer(ass, testSessionMethods(ass, map[string]testMethod{
+		"Test.call": func(params map[string]interface{}) (interface{}, *Error) {
+			calls = append(calls, "Test.call")
+			ass.Equal("session", params["_session_id_"])
+			ass.Equal("aaa", params["param"])
+
+			// first call fails with expired session
+			if expired {
+				expired = false
+				return nil, &Error{
+					Name:    "JSONRPCError",
+					Code:    400,
+					Message: "access denied ( 0 )",
+				}
+			}
+			return 42, nil
+		},
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "user", "pass")
+
+	var result int
+	ass.NoError(c.Call("Test.call", map[string]interface{}{
+		"param": "aaa",
+	}, &result))
+	ass.Equal(42, result)
+	ass.Equal([]string{"Test.call", "Test.call"}, calls)
+
+	err := c.Call("Test.unknown", nil, nil)
+	ass.EqualError(err, "method not found (501)")
+	ass.IsType(&Error{}, err)
+
+	ass.NoError(c.Logout())
+	ass.Equal("", c.(*client).sessionID)
+	ass.NoError(c.Logout())
+}
+
+func TestClient_Login(t *testing.T) {
+	ass := assert.New(t)
+
+	server := testServer(ass, map[string]testMethod{
+		"Session.login": func(params map[string]interface{}) (interface{}, *Error) {
+			return nil, &Error{
+				Name:    "JSONRPCError",
+				Code:    501,
+				Message: "invalid credentials",
+			}
+		},
+	})
+	defer server.Close()
+
+	c := NewClient(server.URL, "user", "wrong")
+	ass.EqualError(c.Login(), "invalid credentials (501)")
+	ass.EqualError(c.Call("Test.call", nil, nil), "invalid credentials (501)")
+}
+
+func TestClient_ensureSession(t *testing.T) {
+	ass := assert.New(t)
+
+	var renewed int
+	server := testServer(ass, testSessionMethods(ass, map[string]testMethod{
+		"Session.renew": func(params map[string]interface{}) (interface{}, *Error) {
+			ass.Equal("session", params["_session_id_"])
+			renewed++
+			return true, nil
+		},
+		"Test.call": func(params map[string]interface{}) (interface{}, *Error) {
+			return true, nil
+		},
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "user", "pass")
+	ass.NoError(c.Login())
+
+	// no renew directly after login
+	ass.NoError(c.Call("Test.call", nil, nil))
+	ass.Equal(0, renewed)
+
+	c.(*client).lastActivity = time.Now().Add(-time.Hour)
+	ass.NoError(c.Call("Test.call", nil, nil))
+	ass.Equal(1, renewed)
+}
+
+func TestClient_invalidStatus(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "user", "pass")
+	ass.EqualError(c.Login(), "invalid response status 404 Not Found")
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Bool accepts JSON booleans and strings ("true"/"false")
+// -> the API is not consistent in the type of flags
+type Bool bool
+
+// UnmarshalJSON from boolean or string
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseBool(strings.Trim(string(data), `"`))
+	if err != nil {
+		// handle empty strings and null as false
+		*b = false
+		return nil
+	}
+	*b = Bool(value)
+	return nil
+}
+
+// DeviceDescription of interface process returned by Interface.listDevices
+type DeviceDescription struct {
+	Address   string   `json:"address"`
+	Type      string   `json:"type"`
+	Parent    string   `json:"parent"`
+	Children  []string `json:"children"`
+	ParamSets []string `json:"paramsets"`
+	Version   int      `json:"version"`
+	Flags     int      `json:"flags"`
+}
+
+// Device of the logic layer returned by Device.listAllDetail
+type Device struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Interface string    `json:"interface"`
+	Type      string    `json:"type"`
+	IsReady   Bool      `json:"isReady"`
+	Channels  []Channel `json:"channels"`
+}
+
+// Channel of a logic layer device
+type Channel struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	DeviceID    string `json:"deviceId"`
+	Index       int    `json:"index"`
+	Category    string `json:"category"`
+	ChannelType string `json:"channelType"`
+
+	IsReady     Bool `json:"isReady"`
+	IsUsable    Bool `json:"isUsable"`
+	IsVisible   Bool `json:"isVisible"`
+	IsReadable  Bool `json:"isReadable"`
+	IsWritable  Bool `json:"isWritable"`
+	IsEventable Bool `json:"isEventable"`
+}
+
+// Room of the logic layer returned by Room.getAll
+type Room struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ChannelIDs  []string `json:"channelIds"`
+}
+
+// SysVar is a system variable returned by SysVar.getAll
+type SysVar struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Unit       string      `json:"unit"`
+	Value      interface{} `json:"value"`
+	ValueList  string      `json:"valueList"`
+	MinValue   interface{} `json:"minValue"`
+	MaxValue   interface{} `json:"maxValue"`
+	IsLogged   Bool        `json:"isLogged"`
+	IsVisible  Bool        `json:"isVisible"`
+	IsInternal Bool        `json:"isInternal"`
+}
+
+// InterfaceListDevices returns all devices of the interface (e.g. BidCos-RF)
+func (c *client) InterfaceListDevices(iface string) ([]DeviceDescription, error) {
+	var devices []DeviceDescription
+	err := c.Call("Interface.listDevices", map[string]interface{}{
+		"interface": iface,
+	}, &devices)
+	return devices, err
+}
+
+// DeviceListAllDetail returns all devices of the logic layer
+func (c *client) DeviceListAllDetail() ([]Device, error) {
+	var devices []Device
+	err := c.Call("Device.listAllDetail", nil, &devices)
+	return devices, err
+}
+
+// RoomGetAll returns all rooms
+func (c *client) RoomGetAll() ([]Room, error) {
+	var rooms []Room
+	err := c.Call("Room.getAll", nil, &rooms)
+	return rooms, err
+}
+
+// SysVarGetAll returns all system variables
+func (c *client) SysVarGetAll() ([]SysVar, error) {
+	var sysVars []SysVar
+	err := c.Call("SysVar.getAll", nil, &sysVars)
+	return sysVars, err
+}
+
+// ReGaRunScript executes the script and returns its output
+func (c *client) ReGaRunScript(script string) (string, error) {
+	var output json.RawMessage
+	err := c.Call("ReGa.runScript", map[string]interface{}{
+		"script": script,
+	}, &output)
+	if err != nil {
+		return "", err
+	}
+
+	// output is a string or null if nothing was written
+	var str string
+	if len(output) > 0 && string(output) != "null" {
+		err = json.Unmarshal(output, &str)
+	}
+	return str, err
+}
+
+// DeviceNames returns the names of all devices and channels by address
+func DeviceNames(ctx context.Context, c Client) (map[string]string, error) {
+	var devices []Device
+	err := c.CallContext(ctx, "Device.listAllDetail", nil, &devices)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(devices))
+	for _, device := range devices {
+		names[device.Address] = device.Name
+		for _, channel := range device.Channels {
+			names[channel.Address] = channel.Name
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,162 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBool_UnmarshalJSON(t *testing.T) {
+	ass := assert.New(t)
+
+	var data struct {
+		A Bool
+		B Bool
+		C Bool
+		D Bool
+	}
+	ass.NoError(json.Unmarshal(
+		[]byte(`{"A": true, "B": "true", "C": "false", "D": ""}`), &data))
+	ass.True(bool(data.A))
+	ass.True(bool(data.B))
+	ass.False(bool(data.C))
+	ass.False(bool(data.D))
+}
+
+func TestClient_methods(t *testing.T) {
+	ass := assert.New(t)
+
+	server := testServer(ass, testSessionMethods(ass, map[string]testMethod{
+		"Interface.listDevices": func(params map[string]interface{}) (interface{}, *Error) {
+			ass.Equal("BidCos-RF", params["interface"])
+			return []interface{}{
+				map[string]interface{}{
+					"address":   "OEQ1234567",
+					"type":      "HM-LC-Sw1-FM",
+					"children":  []string{"OEQ1234567:1"},
+					"paramsets": []string{"MASTER"},
+					"version":   5,
+				},
+			}, nil
+		},
+		"Device.listAllDetail": func(params map[string]interface{}) (interface{}, *Error) {
+			return []interface{}{
+				map[string]interface{}{
+					"id":        "1234",
+					"name":      "Switch",
+					"address":   "OEQ1234567",
+					"interface": "BidCos-RF",
+					"type":      "HM-LC-Sw1-FM",
+					"isReady":   "true",
+					"channels": []interface{}{
+						map[string]interface{}{
+							"id":         "1235",
+							"name":       "Switch:1",
+							"address":    "OEQ1234567:1",
+							"deviceId":   "1234",
+							"index":      1,
+							"isReady":    true,
+							"isWritable": true,
+						},
+					},
+				},
+			}, nil
+		},
+		"Room.getAll": func(params map[string]interface{}) (interface{}, *Error) {
+			return []interface{}{
+				map[string]interface{}{
+					"id":          "1230",
+					"name":        "Living",
+					"description": "",
+					"channelIds":  []string{"1235"},
+				},
+			}, nil
+		},
+		"SysVar.getAll": func(params map[string]interface{}) (interface{}, *Error) {
+			return []interface{}{
+				map[string]interface{}{
+					"id":        "950",
+					"name":      "Presence",
+					"type":      "LOGIC",
+					"value":     "true",
+					"isVisible": true,
+				},
+			}, nil
+		},
+		"ReGa.runScript": func(params map[string]interface{}) (interface{}, *Error) {
+			if params["script"] == "empty" {
+				return nil, nil
+			}
+			ass.Equal(`Write("test");`, params["script"])
+			return "test", nil
+		},
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "user", "pass")
+
+	descriptions, err := c.InterfaceListDevices("BidCos-RF")
+	ass.NoError(err)
+	ass.Equal([]DeviceDescription{{
+		Address:   "OEQ1234567",
+		Type:      "HM-LC-Sw1-FM",
+		Children:  []string{"OEQ1234567:1"},
+		ParamSets: []string{"MASTER"},
+		Version:   5,
+	}}, descriptions)
+
+	devices, err := c.DeviceListAllDetail()
+	ass.NoError(err)
+	ass.Equal([]Device{{
+		ID:        "1234",
+		Name:      "Switch",
+		Address:   "OEQ1234567",
+		Interface: "BidCos-RF",
+		Type:      "HM-LC-Sw1-FM",
+		IsReady:   true,
+		Channels: []Channel{{
+			ID:         "1235",
+			Name:       "Switch:1",
+			Address:    "OEQ1234567:1",
+			DeviceID:   "1234",
+			Index:      1,
+			IsReady:    true,
+			IsWritable: true,
+		}},
+	}}, devices)
+
+	rooms, err := c.RoomGetAll()
+	ass.NoError(err)
+	ass.Equal([]Room{{
+		ID:         "1230",
+		Name:       "Living",
+		ChannelIDs: []string{"1235"},
+	}}, rooms)
+
+	sysVars, err := c.SysVarGetAll()
+	ass.NoError(err)
+	ass.Equal([]SysVar{{
+		ID:        "950",
+		Name:      "Presence",
+		Type:      "LOGIC",
+		Value:     "true",
+		IsVisible: true,
+	}}, sysVars)
+
+	output, err := c.ReGaRunScript(`Write("test");`)
+	ass.NoError(err)
+	ass.Equal("test", output)
+
+	output, err = c.ReGaRunScript("empty")
+	ass.NoError(err)
+	ass.Equal("", output)
+
+	names, err := DeviceNames(context.Background(), c)
+	ass.NoError(err)
+	ass.Equal(map[string]string{
+		"OEQ1234567":   "Switch",
+		"OEQ1234567:1": "Switch:1",
+	}, names)
+}
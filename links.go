@@ -0,0 +1,247 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// flags of getLinks
+const (
+	// links of all channels of a device
+	getLinksFlagGroup = 0x01
+)
+
+// Link is a direct link between a sender and a receiver channel
+type Link struct {
+	Sender      string
+	Receiver    string
+	Name        string
+	Description string
+
+	FlagSenderBroken   bool
+	FlagReceiverBroken bool
+}
+
+// linkDescription as returned by getLinks
+type linkDescription struct {
+	Sender      string `xmlrpc:"SENDER"`
+	Receiver    string `xmlrpc:"RECEIVER"`
+	Name        string `xmlrpc:"NAME"`
+	Description string `xmlrpc:"DESCRIPTION"`
+	Flags       int32  `xmlrpc:"FLAGS"`
+}
+
+// getLinks loads the links of the address (all links if empty)
+func getLinks(ctx context.Context, client rpc.Client, address string, flags int) ([]Link, error) {
+	response, err := client.CallContext(ctx, "getLinks", []interface{}{address, flags})
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+
+	var descriptions []linkDescription
+	err = rpc.Unmarshal(response.FirstParam(), &descriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]Link, len(descriptions))
+	for i, description := range descriptions {
+		links[i] = Link{
+			Sender:      description.Sender,
+			Receiver:    description.Receiver,
+			Name:        description.Name,
+			Description: description.Description,
+
+			FlagSenderBroken:   (description.Flags & 0x01) != 0,
+			FlagReceiverBroken: (description.Flags & 0x02) != 0,
+		}
+	}
+	return links, nil
+}
+
+// GetLinks of the channel or of all channels of the device
+func (d *Device) GetLinks() ([]Link, error) {
+	return d.GetLinksContext(context.Background())
+}
+
+// GetLinksContext of the channel or of all channels of the device and
+// abort if context is done
+func (d *Device) GetLinksContext(ctx context.Context) ([]Link, error) {
+	var flags int
+	if d.GetParent() == "" {
+		flags = getLinksFlagGroup
+	}
+	return getLinks(ctx, d.client, d.Address, flags)
+}
+
+// GetLinkPeers returns the addresses of all channels linked with this channel
+func (d *Device) GetLinkPeers() ([]string, error) {
+	return d.GetLinkPeersContext(context.Background())
+}
+
+// GetLinkPeersContext returns the addresses of all channels linked with this
+// channel and abort if context is done
+func (d *Device) GetLinkPeersContext(ctx context.Context) ([]string, error) {
+	response, err := d.client.CallContext(ctx, "getLinkPeers", []interface{}{d.Address})
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+
+	var peers []string
+	err = rpc.Unmarshal(response.FirstParam(), &peers)
+	return peers, err
+}
+
+// AddLink from this channel as sender to the receiver channel
+func (d *Device) AddLink(receiver, name, description string) error {
+	return d.AddLinkContext(context.Background(), receiver, name, description)
+}
+
+// AddLinkContext from this channel as sender to the receiver channel and
+// abort if context is done
+func (d *Device) AddLinkContext(ctx context.Context, receiver, name, description string) error {
+	response, err := d.client.CallContext(ctx, "addLink", []interface{}{
+		d.Address, receiver, name, description,
+	})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+	return nil
+}
+
+// RemoveLink from this channel as sender to the receiver channel
+func (d *Device) RemoveLink(receiver string) error {
+	return d.RemoveLinkContext(context.Background(), receiver)
+}
+
+// RemoveLinkContext from this channel as sender to the receiver channel and
+// abort if context is done
+func (d *Device) RemoveLinkContext(ctx context.Context, receiver string) error {
+	response, err := d.client.CallContext(ctx, "removeLink", []interface{}{
+		d.Address, receiver,
+	})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+	return nil
+}
+
+// GetLinkParamset returns the LINK paramset of the link with the peer
+// -> paramsets of the link are available with the peer address as key
+func (d *Device) GetLinkParamset(peer string) (map[string]interface{}, error) {
+	return d.GetParamsetContext(context.Background(), peer)
+}
+
+// GetLinkParamsetContext returns the LINK paramset of the link with the peer
+// and abort if context is done
+func (d *Device) GetLinkParamsetContext(ctx context.Context, peer string) (map[string]interface{}, error) {
+	return d.GetParamsetContext(ctx, peer)
+}
+
+// PutLinkParamset writes the values to the LINK paramset of the link with
+// the peer
+func (d *Device) PutLinkParamset(peer string, values map[string]interface{}) error {
+	return d.PutParamsetContext(context.Background(), peer, values)
+}
+
+// PutLinkParamsetContext writes the values to the LINK paramset of the link
+// with the peer and abort if context is done
+func (d *Device) PutLinkParamsetContext(ctx context.Context, peer string, values map[string]interface{}) error {
+	return d.PutParamsetContext(ctx, peer, values)
+}
+
+// GetLinks returns the links of all available interfaces
+func (c *CCU) GetLinks() ([]Link, error) {
+	return c.GetLinksContext(context.Background())
+}
+
+// GetLinksContext returns the links of all available interfaces and
+// abort if context is done
+// -> failed interfaces are skipped (error only if all interfaces failed)
+func (c *CCU) GetLinksContext(ctx context.Context) ([]Link, error) {
+	c.discoverInterfaces(ctx, false)
+	c.clientMutex.RLock()
+	clients := c.availableClients()
+	c.clientMutex.RUnlock()
+	if len(clients) == 0 {
+		return nil, errors.New("no interface available")
+	}
+
+	var links []Link
+	var linkErr error
+	var succeeded bool
+	for _, client := range clients {
+		interfaceLinks, err := getLinks(ctx, client, "", 0)
+		if err != nil {
+			// failed interface should not affect the others
+			linkErr = err
+			continue
+		}
+		links = append(links, interfaceLinks...)
+		succeeded = true
+	}
+	if !succeeded {
+		return nil, linkErr
+	}
+	return links, nil
+}
+
+// AddLink from the sender channel to the receiver channel
+func (c *CCU) AddLink(sender, receiver, name, description string) error {
+	return c.AddLinkContext(context.Background(), sender, receiver, name, description)
+}
+
+// AddLinkContext from the sender channel to the receiver channel and
+// abort if context is done
+func (c *CCU) AddLinkContext(ctx context.Context, sender, receiver, name, description string) error {
+	device, err := c.linkSender(ctx, sender)
+	if err != nil {
+		return err
+	}
+	return device.AddLinkContext(ctx, receiver, name, description)
+}
+
+// RemoveLink from the sender channel to the receiver channel
+func (c *CCU) RemoveLink(sender, receiver string) error {
+	return c.RemoveLinkContext(context.Background(), sender, receiver)
+}
+
+// RemoveLinkContext from the sender channel to the receiver channel and
+// abort if context is done
+func (c *CCU) RemoveLinkContext(ctx context.Context, sender, receiver string) error {
+	device, err := c.linkSender(ctx, sender)
+	if err != nil {
+		return err
+	}
+	return device.RemoveLinkContext(ctx, receiver)
+}
+
+// linkSender returns the device of the sender address
+func (c *CCU) linkSender(ctx context.Context, sender string) (*Device, error) {
+	devices, err := c.GetDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.deviceMutex.RLock()
+	device, ok := devices[sender]
+	c.deviceMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("unknown sender " + sender)
+	}
+	return device, nil
+}
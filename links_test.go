@@ -0,0 +1,154 @@
+package homematic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestDevice_links(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls [][]interface{}
+	device := &Device{
+		Address: "sender:1",
+		Parent:  "sender",
+		client: testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			calls = append(calls, append([]interface{}{method}, params...))
+			switch method {
+			case "getLinks":
+				return &rpc.Response{
+					Params: []interface{}{[]interface{}{
+						map[string]interface{}{
+							"SENDER":      "sender:1",
+							"RECEIVER":    "receiver:1",
+							"NAME":        "name",
+							"DESCRIPTION": "description",
+							"FLAGS":       int32(0x02),
+						},
+					}},
+				}, nil
+			case "getLinkPeers":
+				return &rpc.Response{
+					Params: []interface{}{[]interface{}{"receiver:1"}},
+				}, nil
+			case "getParamset":
+				return &rpc.Response{
+					Params: []interface{}{map[string]interface{}{
+						"SHORT_ON_TIME": 10.0,
+					}},
+				}, nil
+			case "removeLink":
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -1, String: "unknown link"},
+				}, nil
+			}
+			return &rpc.Response{}, nil
+		}),
+	}
+
+	links, err := device.GetLinks()
+	ass.NoError(err)
+	ass.Equal([]Link{{
+		Sender:             "sender:1",
+		Receiver:           "receiver:1",
+		Name:               "name",
+		Description:        "description",
+		FlagReceiverBroken: true,
+	}}, links)
+
+	peers, err := device.GetLinkPeers()
+	ass.NoError(err)
+	ass.Equal([]string{"receiver:1"}, peers)
+
+	ass.NoError(device.AddLink("receiver:1", "name", "description"))
+	ass.EqualError(device.RemoveLink("receiver:1"), "unknown link (-1)")
+
+	values, err := device.GetLinkParamset("receiver:1")
+	ass.NoError(err)
+	ass.Equal(map[string]interface{}{"SHORT_ON_TIME": 10.0}, values)
+	ass.NoError(device.PutLinkParamset("receiver:1", values))
+
+	ass.Equal([][]interface{}{
+		{"getLinks", "sender:1", 0},
+		{"getLinkPeers", "sender:1"},
+		{"addLink", "sender:1", "receiver:1", "name", "description"},
+		{"removeLink", "sender:1", "receiver:1"},
+		{"getParamset", "sender:1", "receiver:1"},
+		{"putParamset", "sender:1", "receiver:1", values},
+	}, calls)
+
+	// links of all channels of a device
+	calls = nil
+	device.Address = "sender"
+	device.Parent = ""
+	_, err = device.GetLinks()
+	ass.NoError(err)
+	ass.Equal([][]interface{}{{"getLinks", "sender", 1}}, calls)
+}
+
+func TestCCU_links(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	var calls [][]interface{}
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			calls = append(calls, append([]interface{}{method}, params...))
+			switch method {
+			case "listDevices":
+				return &rpc.Response{
+					Params: []interface{}{[]interface{}{
+						map[string]interface{}{"ADDRESS": "sender:1"},
+					}},
+				}, nil
+			case "getLinks":
+				return &rpc.Response{
+					Params: []interface{}{[]interface{}{
+						map[string]interface{}{
+							"SENDER":   "sender:1",
+							"RECEIVER": "receiver:1",
+						},
+					}},
+				}, nil
+			}
+			return &rpc.Response{}, nil
+		}),
+	}
+	ccu.SetJSONRPCClient(&testJSONClient{})
+
+	links, err := ccu.GetLinks()
+	ass.NoError(err)
+	ass.Equal([]Link{{Sender: "sender:1", Receiver: "receiver:1"}}, links)
+
+	ass.NoError(ccu.AddLink("sender:1", "receiver:1", "name", ""))
+	ass.NoError(ccu.RemoveLink("sender:1", "receiver:1"))
+	ass.EqualError(ccu.RemoveLink("unknown", "receiver:1"), "unknown sender unknown")
+
+	ass.Equal([][]interface{}{
+		{"getLinks", "", 0},
+		{"listDevices"},
+		{"addLink", "sender:1", "receiver:1", "name", ""},
+		{"removeLink", "sender:1", "receiver:1"},
+	}, calls)
+
+	// failed interfaces are skipped
+	ccu.rpcClients["fault"] = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("getLinks", method)
+		return &rpc.Response{
+			Fault: &rpc.Fault{Code: -1, String: "unknown method"},
+		}, nil
+	})
+	links, err = ccu.GetLinks()
+	ass.NoError(err)
+	ass.Equal([]Link{{Sender: "sender:1", Receiver: "receiver:1"}}, links)
+
+	// error if all interfaces failed
+	delete(ccu.rpcClients, "test")
+	_, err = ccu.GetLinks()
+	ass.EqualError(err, "unknown method (-1)")
+}
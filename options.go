@@ -0,0 +1,383 @@
+package homematic
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+	"gitlab.com/bboehmke/homematic/script"
+)
+
+// Option for the connection to a CCU
+type Option func(*options)
+
+// options of the CCU connection
+type options struct {
+	id string
+
+	interfaces    []Interface
+	scriptPort    int
+	scriptTLSPort int
+
+	username   string
+	password   string
+	tls        bool
+	tlsConfig  *tls.Config
+	httpClient *http.Client
+	timeout    time.Duration
+
+	refreshInterval time.Duration
+	eventTimeout    time.Duration
+
+	pingInterval     time.Duration
+	pingTimeout      time.Duration
+	minReinitBackoff time.Duration
+	maxReinitBackoff time.Duration
+
+	dispatchWorkers   int
+	dispatchQueueSize int
+	panicHandler      func(event Event, recovered interface{})
+
+	cacheFallback       bool
+	snapshotConcurrency int
+
+	pollingMode   PollingMode
+	pollInterval  time.Duration
+	pollIntervals map[string]time.Duration
+
+	listenAddress    string
+	binListenAddress string
+	advertiseAddress string
+	callbackURL      string
+}
+
+// defaultOptions used if not changed by an option
+func defaultOptions() *options {
+	return &options{
+		id:              "go",
+		interfaces:      []Interface{InterfaceWired, InterfaceRF, InterfaceHmIP},
+		scriptPort:      8181,
+		scriptTLSPort:   48181,
+		timeout:         time.Second * 5,
+		refreshInterval: time.Minute * 10,
+		eventTimeout:    time.Minute * 10,
+		listenAddress:   "0.0.0.0:0",
+
+		pingInterval:     time.Second * 30,
+		pingTimeout:      time.Second * 10,
+		minReinitBackoff: time.Second * 5,
+		maxReinitBackoff: time.Minute * 5,
+
+		dispatchWorkers:   4,
+		dispatchQueueSize: 100,
+
+		pollInterval: time.Minute,
+	}
+}
+
+// WithID sets the id used to register on the interfaces (default "go")
+func WithID(id string) Option {
+	return func(o *options) {
+		o.id = id
+	}
+}
+
+// WithInterfaces sets the interface processes used
+// (default InterfaceWired, InterfaceRF and InterfaceHmIP)
+func WithInterfaces(interfaces ...Interface) Option {
+	return func(o *options) {
+		o.interfaces = interfaces
+	}
+}
+
+// WithScriptPort sets the plain and TLS port of the remote script interface
+// (default 8181 and 48181)
+func WithScriptPort(port, tlsPort int) Option {
+	return func(o *options) {
+		o.scriptPort = port
+		o.scriptTLSPort = tlsPort
+	}
+}
+
+// WithCredentials for authentication on the RPC and script ports
+func WithCredentials(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithTLS uses the TLS ports of the CCU (e.g. 42001 instead of 2001)
+// -> config can be nil or contain a custom CA or InsecureSkipVerify
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = true
+		o.tlsConfig = config
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for XML RPC and script requests
+// -> the TLS configuration of WithTLS is not applied to this client
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithRoundTripper sets the transport used for XML RPC and script requests
+func WithRoundTripper(transport http.RoundTripper) Option {
+	return WithHTTPClient(&http.Client{
+		Transport: transport,
+	})
+}
+
+// WithTimeout sets the timeout of calls without a context deadline
+// (default 5 seconds)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRefreshInterval sets the minimal time between two device list updates
+// (default 10 minutes)
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.refreshInterval = interval
+	}
+}
+
+// WithEventTimeout sets the time without events after which the callback
+// is registered again on an interface without ping support (default 10 minutes)
+func WithEventTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.eventTimeout = timeout
+	}
+}
+
+// WithPingInterval sets the interval the callback connection of each
+// interface is checked with a ping (default 30 seconds)
+func WithPingInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pingInterval = interval
+	}
+}
+
+// WithPingTimeout sets the time to wait for the PONG event of a ping
+// (default 10 seconds)
+func WithPingTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.pingTimeout = timeout
+	}
+}
+
+// WithReinitBackoff sets the minimal and maximal delay between two
+// registrations on an interface with a broken callback connection
+// (default 5 seconds and 5 minutes)
+func WithReinitBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.minReinitBackoff = minBackoff
+		o.maxReinitBackoff = maxBackoff
+	}
+}
+
+// WithDispatcher sets the number of workers calling the value changed
+// handlers and the number of events queued per worker (default 4 and 100)
+// -> events of the same device are always handled in order by one worker
+// -> events are dropped if the queue of the worker is full (at least 1)
+func WithDispatcher(workers, queueSize int) Option {
+	return func(o *options) {
+		o.dispatchWorkers = workers
+		o.dispatchQueueSize = queueSize
+	}
+}
+
+// WithPanicHandler sets a function called if a value changed handler or
+// a device hook panics
+func WithPanicHandler(handler func(event Event, recovered interface{})) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
+
+// WithCacheFallback enables loading the values from the CCU if no value
+// of a device is cached (see Device.CachedValues)
+func WithCacheFallback(enabled bool) Option {
+	return func(o *options) {
+		o.cacheFallback = enabled
+	}
+}
+
+// WithSnapshot enables loading the values of all devices after start and
+// after an interface was registered again with the given number of
+// parallel requests (see CCU.Snapshot)
+func WithSnapshot(concurrency int) Option {
+	return func(o *options) {
+		o.snapshotConcurrency = concurrency
+	}
+}
+
+// WithPolling sets when the values of the devices are polled
+// (default PollingAuto -> only if the callback connection is broken)
+// -> changed values are sent as synthetic events
+func WithPolling(mode PollingMode) Option {
+	return func(o *options) {
+		o.pollingMode = mode
+	}
+}
+
+// WithPollInterval sets the interval the values of the devices are polled
+// for the interfaces with the given names (e.g. "rf") or of all interfaces
+// if no name is given (default 1 minute)
+// -> can be changed per device with Device.SetPollInterval
+func WithPollInterval(interval time.Duration, interfaces ...string) Option {
+	return func(o *options) {
+		if len(interfaces) == 0 {
+			o.pollInterval = interval
+			return
+		}
+		if o.pollIntervals == nil {
+			o.pollIntervals = make(map[string]time.Duration, len(interfaces))
+		}
+		for _, name := range interfaces {
+			o.pollIntervals[name] = interval
+		}
+	}
+}
+
+// WithCallbackListenAddress sets the address the callback server listens on
+// (default "0.0.0.0:0" -> all IPv4 interfaces on a random port)
+// -> "[::]:9000" listens on IPv6 and ":9000" on IPv4 and IPv6
+func WithCallbackListenAddress(address string) Option {
+	return func(o *options) {
+		o.listenAddress = address
+	}
+}
+
+// WithBinCallbackListenAddress sets the address the callback server for
+// binary RPC interfaces (e.g. CUxD) listens on
+// (default host of WithCallbackListenAddress on a random port)
+func WithBinCallbackListenAddress(address string) Option {
+	return func(o *options) {
+		o.binListenAddress = address
+	}
+}
+
+// WithCallbackAdvertiseAddress sets the host or host:port sent to the CCU
+// as callback address (e.g. the host address if running in a container)
+// -> by default the local IP of the connection to the CCU and the port of
+// the callback server is used
+// -> binary RPC interfaces only use the host with the port of their server
+func WithCallbackAdvertiseAddress(address string) Option {
+	return func(o *options) {
+		o.advertiseAddress = address
+	}
+}
+
+// WithCallbackURL sets the full URL sent to the CCU as callback address
+// (e.g. "http://homematic.example.com:9000" behind a reverse proxy)
+// -> overrides WithCallbackAdvertiseAddress (not for binary RPC interfaces)
+func WithCallbackURL(url string) Option {
+	return func(o *options) {
+		o.callbackURL = url
+	}
+}
+
+// interfacePollInterval returns the poll interval of the interface
+func (o *options) interfacePollInterval(name string) time.Duration {
+	if interval, ok := o.pollIntervals[name]; ok {
+		return interval
+	}
+	return o.pollInterval
+}
+
+// url for the given host and plain or TLS port
+// -> plain port is used if no TLS port is available
+func (o *options) url(address string, port, tlsPort int) string {
+	if o.tls && tlsPort != 0 {
+		return fmt.Sprintf("https://%s/", net.JoinHostPort(address, strconv.Itoa(tlsPort)))
+	}
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(address, strconv.Itoa(port)))
+}
+
+// interfaceURL of the interface on the given host
+func (o *options) interfaceURL(address string, iface Interface) string {
+	url := o.url(address, iface.Port, iface.TLSPort)
+	if iface.Binary {
+		// binary RPC has no HTTP scheme
+		url = "xmlrpc_bin" + url[strings.Index(url, "://"):]
+	}
+	if iface.Path != "" || iface.Binary {
+		url = strings.TrimSuffix(url, "/") + iface.Path
+	}
+	return url
+}
+
+// binCallbackListenAddress for the callback server of binary RPC interfaces
+func (o *options) binCallbackListenAddress() string {
+	if o.binListenAddress != "" {
+		return o.binListenAddress
+	}
+	host, _, err := net.SplitHostPort(o.listenAddress)
+	if err != nil {
+		return "0.0.0.0:0"
+	}
+	return net.JoinHostPort(host, "0")
+}
+
+// interfaceClient creates the RPC client for the interface on the given host
+func (o *options) interfaceClient(address string, iface Interface) rpc.Client {
+	url := o.interfaceURL(address, iface)
+	clientOptions := o.rpcClientOptions(o.tls && iface.TLSPort != 0)
+	if iface.Binary {
+		return rpc.NewBinClient(url, clientOptions...)
+	}
+	return rpc.NewClient(url, clientOptions...)
+}
+
+// rpcClientOptions for the RPC clients with or without TLS
+func (o *options) rpcClientOptions(useTLS bool) []rpc.ClientOption {
+	clientOptions := []rpc.ClientOption{
+		rpc.WithTimeout(o.timeout),
+	}
+	if o.username != "" || o.password != "" {
+		clientOptions = append(clientOptions, rpc.WithBasicAuth(o.username, o.password))
+	}
+	if useTLS {
+		config := o.tlsConfig
+		if config == nil {
+			config = new(tls.Config)
+		}
+		clientOptions = append(clientOptions, rpc.WithTLSConfig(config))
+	}
+	if o.httpClient != nil {
+		clientOptions = append(clientOptions, rpc.WithHTTPClient(o.httpClient))
+	}
+	return clientOptions
+}
+
+// scriptClientOptions for the remote script client
+func (o *options) scriptClientOptions() []script.ClientOption {
+	clientOptions := []script.ClientOption{
+		script.WithTimeout(o.timeout),
+	}
+	if o.username != "" || o.password != "" {
+		clientOptions = append(clientOptions, script.WithBasicAuth(o.username, o.password))
+	}
+	if o.tls {
+		config := o.tlsConfig
+		if config == nil {
+			config = new(tls.Config)
+		}
+		clientOptions = append(clientOptions, script.WithTLSConfig(config))
+	}
+	if o.httpClient != nil {
+		clientOptions = append(clientOptions, script.WithHTTPClient(o.httpClient))
+	}
+	return clientOptions
+}
@@ -0,0 +1,184 @@
+package homematic
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_url(t *testing.T) {
+	ass := assert.New(t)
+
+	o := defaultOptions()
+	ass.Equal("http://127.0.0.1:2001/", o.url("127.0.0.1", 2001, 42001))
+	ass.Len(o.rpcClientOptions(o.tls), 1)
+	ass.Len(o.scriptClientOptions(), 1)
+
+	ass.Equal("http://[fe80::1]:2001/", o.url("fe80::1", 2001, 42001))
+
+	WithTLS(nil)(o)
+	WithCredentials("user", "pass")(o)
+	ass.Equal("https://127.0.0.1:42001/", o.url("127.0.0.1", 2001, 42001))
+	ass.Len(o.rpcClientOptions(o.tls), 3)
+	ass.Len(o.scriptClientOptions(), 3)
+
+	WithRoundTripper(http.DefaultTransport)(o)
+	ass.Len(o.rpcClientOptions(o.tls), 4)
+	ass.Len(o.scriptClientOptions(), 4)
+}
+
+func TestNewCCUWithOptions(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithID("test"),
+		WithCredentials("user", "pass"),
+		WithTLS(&tls.Config{InsecureSkipVerify: true}))
+	ass.NoError(err)
+	ass.Len(ccu.rpcClients, 3)
+	ass.Contains(ccu.rpcClients, "test-wired")
+	ass.Contains(ccu.rpcClients, "test-rf")
+	ass.Contains(ccu.rpcClients, "test-hmip")
+	ass.NotNil(ccu.scriptClient)
+}
+
+func TestNewCCUWithOptions_interfaces(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceRF, Interface{Name: "custom", Port: 1234}),
+		WithScriptPort(8282, 48282),
+		WithTimeout(time.Second),
+		WithRefreshInterval(time.Minute),
+		WithEventTimeout(time.Hour),
+		WithCacheFallback(true),
+		WithPolling(PollingAlways),
+		WithPollInterval(time.Second*30),
+		WithPollInterval(time.Hour, "custom"),
+		WithCallbackListenAddress("127.0.0.1:0"))
+	ass.NoError(err)
+	ass.Len(ccu.rpcClients, 2)
+	ass.Contains(ccu.rpcClients, "go-rf")
+	ass.Contains(ccu.rpcClients, "go-custom")
+	ass.Equal(time.Minute, ccu.options.refreshInterval)
+	ass.Equal(time.Hour, ccu.options.eventTimeout)
+	ass.True(ccu.options.cacheFallback)
+	ass.Equal(PollingAlways, ccu.options.pollingMode)
+	ass.Equal(time.Second*30, ccu.options.interfacePollInterval("rf"))
+	ass.Equal(time.Hour, ccu.options.interfacePollInterval("custom"))
+	ass.Equal(8282, ccu.options.scriptPort)
+}
+
+func TestCCU_callbackURL(t *testing.T) {
+	ass := assert.New(t)
+
+	var client testRpcClient
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+	url, err := ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal(fmt.Sprintf("http://127.0.0.1:%d", ccu.rpcServer.Port()), url)
+
+	ccu, err = NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("192.168.1.10"))
+	ass.NoError(err)
+	url, err = ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal(fmt.Sprintf("http://192.168.1.10:%d", ccu.rpcServer.Port()), url)
+
+	ccu, err = NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("192.168.1.10:8080"))
+	ass.NoError(err)
+	url, err = ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal("http://192.168.1.10:8080", url)
+}
+
+func TestCCU_callbackURL_IPv6(t *testing.T) {
+	ass := assert.New(t)
+
+	var client testRpcClient
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("fe80::1"))
+	ass.NoError(err)
+	url, err := ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal(fmt.Sprintf("http://[fe80::1]:%d", ccu.rpcServer.Port()), url)
+
+	ccu, err = NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("[fe80::1]"))
+	ass.NoError(err)
+	url, err = ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal(fmt.Sprintf("http://[fe80::1]:%d", ccu.rpcServer.Port()), url)
+
+	ccu, err = NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("[fe80::1]:9000"))
+	ass.NoError(err)
+	url, err = ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal("http://[fe80::1]:9000", url)
+
+	// full URL overrides advertise address
+	ccu, err = NewCCUWithOptions("127.0.0.1",
+		WithCallbackAdvertiseAddress("192.168.1.10"),
+		WithCallbackURL("https://proxy.example.com/homematic"))
+	ass.NoError(err)
+	url, err = ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal("https://proxy.example.com/homematic", url)
+}
+
+func TestOptions_interfaceURL(t *testing.T) {
+	ass := assert.New(t)
+
+	o := defaultOptions()
+	ass.Equal("http://127.0.0.1:2001/", o.interfaceURL("127.0.0.1", InterfaceRF))
+	ass.Equal("xmlrpc_bin://127.0.0.1:8701", o.interfaceURL("127.0.0.1", InterfaceCUxD))
+	ass.Equal("http://127.0.0.1:9292/groups", o.interfaceURL("127.0.0.1", InterfaceVirtualDevices))
+
+	WithTLS(nil)(o)
+	ass.Equal("https://127.0.0.1:42001/", o.interfaceURL("127.0.0.1", InterfaceRF))
+	ass.Equal("xmlrpc_bin://127.0.0.1:8701", o.interfaceURL("127.0.0.1", InterfaceCUxD))
+	ass.Equal("https://127.0.0.1:49292/groups", o.interfaceURL("127.0.0.1", InterfaceVirtualDevices))
+
+	ass.Equal("0.0.0.0:0", o.binCallbackListenAddress())
+	WithCallbackListenAddress("[::]:9000")(o)
+	ass.Equal("[::]:0", o.binCallbackListenAddress())
+	WithBinCallbackListenAddress("127.0.0.1:9001")(o)
+	ass.Equal("127.0.0.1:9001", o.binCallbackListenAddress())
+}
+
+func TestNewCCUWithOptions_binary(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceRF, InterfaceCUxD, InterfaceVirtualDevices),
+		WithCallbackAdvertiseAddress("192.168.1.10:9000"))
+	ass.NoError(err)
+	ass.Len(ccu.rpcClients, 3)
+	ass.Equal(InterfaceCUxD, ccu.interfaces["go-cuxd"])
+	ass.NotNil(ccu.binServer)
+	ass.True(ccu.binServer.IsBinary())
+
+	var client testRpcClient
+	url, err := ccu.callbackURL("go-rf", client)
+	ass.NoError(err)
+	ass.Equal("http://192.168.1.10:9000", url)
+
+	// binary interfaces use the port of the binary server
+	url, err = ccu.callbackURL("go-cuxd", client)
+	ass.NoError(err)
+	ass.Equal(fmt.Sprintf("xmlrpc_bin://192.168.1.10:%d", ccu.binServer.Port()), url)
+
+	// without binary interface no binary server is created
+	ccu, err = NewCCU("127.0.0.1")
+	ass.NoError(err)
+	ass.Nil(ccu.binServer)
+}
@@ -0,0 +1,271 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// size of the channel buffer of devices waiting for new devices
+// -> devices are dropped if the buffer is full
+const newDeviceBufferSize = 10
+
+// InstallMode of setInstallMode
+type InstallMode int
+
+// modes of the install mode
+const (
+	// InstallModeNormal pairs devices with their current configuration
+	InstallModeNormal InstallMode = 1
+	// InstallModeReset resets the MASTER paramsets of paired devices
+	InstallModeReset InstallMode = 2
+)
+
+// DeleteFlag of deleteDevice
+type DeleteFlag int
+
+// flags of deleteDevice
+const (
+	// DeleteFlagReset resets the device to factory defaults
+	DeleteFlagReset DeleteFlag = 0x01
+	// DeleteFlagForce deletes the device even if it is not reachable
+	DeleteFlagForce DeleteFlag = 0x02
+	// DeleteFlagDefer deletes the device as soon as it is reachable
+	DeleteFlagDefer DeleteFlag = 0x04
+)
+
+// interfaceByName returns the id and the client of the interface with the id
+// (e.g. "go-rf") or name (e.g. "rf")
+func (c *CCU) interfaceByName(name string) (string, rpc.Client, error) {
+	c.clientMutex.RLock()
+	defer c.clientMutex.RUnlock()
+
+	if client, ok := c.rpcClients[name]; ok {
+		return name, client, nil
+	}
+	for id, iface := range c.interfaces {
+		if iface.Name == name {
+			return id, c.rpcClients[id], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown interface %s", name)
+}
+
+// callInterface calls the method on the interface and returns the fault
+// of the response as error
+func (c *CCU) callInterface(ctx context.Context, name, method string, params []interface{}) (*rpc.Response, error) {
+	_, client, err := c.interfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.CallContext(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+	return response, nil
+}
+
+// SetInstallMode enables or disables the install mode of the interface
+// (id like "go-rf" or name like "rf") for the given duration
+func (c *CCU) SetInstallMode(iface string, on bool, duration time.Duration, mode InstallMode) error {
+	return c.SetInstallModeContext(context.Background(), iface, on, duration, mode)
+}
+
+// SetInstallModeContext enables or disables the install mode of the
+// interface for the given duration and aborts if context is done
+func (c *CCU) SetInstallModeContext(ctx context.Context, iface string, on bool, duration time.Duration, mode InstallMode) error {
+	_, err := c.callInterface(ctx, iface, "setInstallMode", []interface{}{
+		on, int(duration / time.Second), int(mode),
+	})
+	return err
+}
+
+// GetInstallMode returns the remaining time of the install mode of the
+// interface (0 if disabled)
+func (c *CCU) GetInstallMode(iface string) (time.Duration, error) {
+	return c.GetInstallModeContext(context.Background(), iface)
+}
+
+// GetInstallModeContext returns the remaining time of the install mode of
+// the interface and aborts if context is done
+func (c *CCU) GetInstallModeContext(ctx context.Context, iface string) (time.Duration, error) {
+	response, err := c.callInterface(ctx, iface, "getInstallMode", nil)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := cast.ToIntE(response.FirstParam())
+	return time.Duration(seconds) * time.Second, err
+}
+
+// AddDeviceBySerial pairs the device with the serial number on the interface
+// without install mode (HomeMatic RF)
+func (c *CCU) AddDeviceBySerial(iface, serial string) error {
+	return c.AddDeviceBySerialContext(context.Background(), iface, serial)
+}
+
+// AddDeviceBySerialContext pairs the device with the serial number on the
+// interface and aborts if context is done
+func (c *CCU) AddDeviceBySerialContext(ctx context.Context, iface, serial string) error {
+	_, err := c.callInterface(ctx, iface, "addDevice", []interface{}{serial})
+	return err
+}
+
+// AddHmIPDevice enables the install mode of the HomeMatic IP interface only
+// for the device with the SGTIN and key for the given duration
+func (c *CCU) AddHmIPDevice(iface, sgtin, key string, duration time.Duration) error {
+	return c.AddHmIPDeviceContext(context.Background(), iface, sgtin, key, duration)
+}
+
+// AddHmIPDeviceContext enables the install mode of the HomeMatic IP interface
+// only for the device with the SGTIN and key and aborts if context is done
+func (c *CCU) AddHmIPDeviceContext(ctx context.Context, iface, sgtin, key string, duration time.Duration) error {
+	_, err := c.callInterface(ctx, iface, "setInstallModeWithWhitelist", []interface{}{
+		true,
+		int(duration / time.Second),
+		[]interface{}{
+			map[string]interface{}{
+				"ADDRESS":  strings.ToUpper(strings.Replace(sgtin, "-", "", -1)),
+				"KEY_MODE": "LOCAL",
+				"KEY":      key,
+			},
+		},
+	})
+	return err
+}
+
+// DeleteDevice removes the device with the address from the CCU
+func (c *CCU) DeleteDevice(address string, flags DeleteFlag) error {
+	return c.DeleteDeviceContext(context.Background(), address, flags)
+}
+
+// DeleteDeviceContext removes the device with the address from the CCU
+// and aborts if context is done
+// -> device is removed from the device list with the deleteDevices callback
+func (c *CCU) DeleteDeviceContext(ctx context.Context, address string, flags DeleteFlag) error {
+	devices, err := c.GetDevicesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.deviceMutex.RLock()
+	device, ok := devices[address]
+	c.deviceMutex.RUnlock()
+	if !ok {
+		return errors.New("unknown device " + address)
+	}
+
+	response, err := device.client.CallContext(ctx, "deleteDevice", []interface{}{
+		address, int(flags),
+	})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+	return nil
+}
+
+// waitNewDevice registers a channel that receives devices added by a
+// newDevices callback
+func (c *CCU) waitNewDevice() chan *Device {
+	ch := make(chan *Device, newDeviceBufferSize)
+
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	c.newDeviceWaiters[ch] = true
+	return ch
+}
+
+// cancelNewDevice removes the channel registered with waitNewDevice
+func (c *CCU) cancelNewDevice(ch chan *Device) {
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	delete(c.newDeviceWaiters, ch)
+}
+
+// newDeviceAdded sends the device to all waiting channels
+// -> channels are ignored
+func (c *CCU) newDeviceAdded(device *Device) {
+	if device.GetParent() != "" {
+		return
+	}
+
+	c.pairingMutex.Lock()
+	defer c.pairingMutex.Unlock()
+
+	for ch := range c.newDeviceWaiters {
+		select {
+		case ch <- device:
+		default:
+		}
+	}
+}
+
+// WaitForNewDevice blocks until a device is added by a newDevices callback
+// and returns it
+// -> address filters for a device (serial number or SGTIN), empty for any
+// -> channels of the device are not returned
+func (c *CCU) WaitForNewDevice(ctx context.Context, address string) (*Device, error) {
+	ch := c.waitNewDevice()
+	defer c.cancelNewDevice(ch)
+
+	return receiveNewDevice(ctx, ch, "", address)
+}
+
+// PairDevice enables the install mode of the interface and blocks until a
+// device is paired on this interface (install mode is disabled on return)
+func (c *CCU) PairDevice(ctx context.Context, iface string, duration time.Duration, mode InstallMode) (*Device, error) {
+	id, _, err := c.interfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait before install mode is enabled -> no device is missed
+	ch := c.waitNewDevice()
+	defer c.cancelNewDevice(ch)
+
+	err = c.SetInstallModeContext(ctx, iface, true, duration, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	device, err := receiveNewDevice(ctx, ch, id, "")
+
+	// install mode could already be disabled by the CCU
+	disableCtx, disableCancel := context.WithTimeout(context.Background(), c.options.timeout)
+	defer disableCancel()
+	_ = c.SetInstallModeContext(disableCtx, iface, false, 0, mode)
+	return device, err
+}
+
+// receiveNewDevice waits for a device of the interface id with the address
+// on the channel (empty id or address for any)
+func receiveNewDevice(ctx context.Context, ch chan *Device, id, address string) (*Device, error) {
+	address = strings.ToUpper(strings.Replace(address, "-", "", -1))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case device := <-ch:
+			if (id == "" || device.Interface == id) &&
+				(address == "" || device.Address == address) {
+				return device, nil
+			}
+		}
+	}
+}
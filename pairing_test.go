@@ -0,0 +1,134 @@
+package homematic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestCCU_installMode(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1", WithInterfaces(InterfaceRF, InterfaceHmIP))
+	ass.NoError(err)
+
+	var calls [][]interface{}
+	client := testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		calls = append(calls, append([]interface{}{method}, params...))
+		switch method {
+		case "getInstallMode":
+			return &rpc.Response{Params: []interface{}{int32(42)}}, nil
+		case "addDevice":
+			return &rpc.Response{
+				Fault: &rpc.Fault{Code: -1, String: "device not found"},
+			}, nil
+		}
+		return &rpc.Response{}, nil
+	})
+	ccu.rpcClients["go-rf"] = client
+	ccu.rpcClients["go-hmip"] = client
+
+	ass.NoError(ccu.SetInstallMode("rf", true, time.Minute, InstallModeNormal))
+	remaining, err := ccu.GetInstallMode("go-rf")
+	ass.NoError(err)
+	ass.Equal(time.Second*42, remaining)
+	ass.EqualError(ccu.AddDeviceBySerial("rf", "OEQ1234567"), "device not found (-1)")
+	ass.NoError(ccu.AddHmIPDevice("hmip", "3014-F711-A000-0000-0000-0001", "key", time.Minute))
+	ass.EqualError(ccu.SetInstallMode("wired", true, time.Minute, InstallModeNormal),
+		"unknown interface wired")
+
+	ass.Equal([][]interface{}{
+		{"setInstallMode", true, 60, 1},
+		{"getInstallMode"},
+		{"addDevice", "OEQ1234567"},
+		{"setInstallModeWithWhitelist", true, 60, []interface{}{
+			map[string]interface{}{
+				"ADDRESS":  "3014F711A000000000000001",
+				"KEY_MODE": "LOCAL",
+				"KEY":      "key",
+			},
+		}},
+	}, calls)
+}
+
+func TestCCU_DeleteDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+	ccu.lastUpdate = time.Now()
+
+	var calls [][]interface{}
+	ccu.devices["address"] = &Device{
+		Address: "address",
+		client: testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			calls = append(calls, append([]interface{}{method}, params...))
+			return &rpc.Response{}, nil
+		}),
+	}
+
+	ass.NoError(ccu.DeleteDevice("address", DeleteFlagReset|DeleteFlagDefer))
+	ass.EqualError(ccu.DeleteDevice("unknown", 0), "unknown device unknown")
+	ass.Equal([][]interface{}{
+		{"deleteDevice", "address", 5},
+	}, calls)
+}
+
+func TestCCU_PairDevice(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+	ccu.SetJSONRPCClient(&testJSONClient{})
+
+	installMode := make(chan bool, 2)
+	ccu.rpcClients["go-rf"] = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("setInstallMode", method)
+		installMode <- params[0].(bool)
+		if params[0].(bool) {
+			// device is paired while install mode is active
+			// -> devices of other interfaces are ignored
+			go func() {
+				ccu.callbackNewDevices([]interface{}{
+					"go-hmip",
+					[]interface{}{
+						map[string]interface{}{
+							"ADDRESS": "000A1234567890",
+						},
+					},
+				})
+				ccu.callbackNewDevices([]interface{}{
+					"go-rf",
+					[]interface{}{
+						map[string]interface{}{
+							"ADDRESS": "OEQ1234567:1",
+							"PARENT":  "OEQ1234567",
+						},
+						map[string]interface{}{
+							"ADDRESS": "OEQ1234567",
+						},
+					},
+				})
+			}()
+		}
+		return &rpc.Response{}, nil
+	})
+
+	device, err := ccu.PairDevice(context.Background(), "rf", time.Second, InstallModeNormal)
+	ass.NoError(err)
+	ass.Equal("OEQ1234567", device.Address)
+	ass.True(<-installMode)
+	ass.False(<-installMode)
+	ass.Empty(ccu.newDeviceWaiters)
+
+	// wait for a specific device
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	go ccu.newDeviceAdded(&Device{Address: "OEQ7654321"})
+	_, err = ccu.WaitForNewDevice(ctx, "OEQ1111111")
+	ass.Equal(context.DeadlineExceeded, err)
+}
@@ -0,0 +1,314 @@
+package homematic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// keys of the paramsets of a device
+const (
+	ParamsetValues = "VALUES"
+	ParamsetMaster = "MASTER"
+	ParamsetLink   = "LINK"
+)
+
+// names of common MASTER parameters
+const (
+	ParameterTransmitAttempts  = "TRANSMIT_TRY_MAX"
+	ParameterButtonLock        = "BUTTON_LOCK"
+	ParameterTemperatureOffset = "TEMPERATURE_OFFSET"
+)
+
+// ParameterError is returned if parameters are not part of a paramset
+// or can not be written
+type ParameterError struct {
+	Paramset   string
+	Parameters []string
+}
+
+// Error returns the invalid parameters
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("invalid parameter %s in paramset %s",
+		strings.Join(e.Parameters, ", "), e.Paramset)
+}
+
+// HasParamset returns true if device has the paramset with the key
+func (d *Device) HasParamset(key string) bool {
+	for _, p := range d.GetParamSets() {
+		if p == key {
+			return true
+		}
+	}
+	return false
+}
+
+// GetParamset returns the values of the paramset with the key
+// (e.g. ParamsetMaster)
+func (d *Device) GetParamset(key string) (map[string]interface{}, error) {
+	return d.GetParamsetContext(context.Background(), key)
+}
+
+// GetParamsetContext returns the values of the paramset with the key
+// and aborts if context is done
+func (d *Device) GetParamsetContext(ctx context.Context, key string) (map[string]interface{}, error) {
+	response, err := d.client.CallContext(ctx,
+		"getParamset",
+		[]interface{}{d.Address, key})
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+	return cast.ToStringMap(response.FirstParam()), nil
+}
+
+// PutParamset writes the values to the paramset with the key
+// -> only the given parameters are changed
+func (d *Device) PutParamset(key string, values map[string]interface{}) error {
+	return d.PutParamsetContext(context.Background(), key, values)
+}
+
+// PutParamsetContext writes the values to the paramset with the key
+// and aborts if context is done
+func (d *Device) PutParamsetContext(ctx context.Context, key string, values map[string]interface{}) error {
+	response, err := d.client.CallContext(ctx,
+		"putParamset",
+		[]interface{}{d.Address, key, values})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+	return nil
+}
+
+// GetParamsetDescription returns the description of all parameters of the
+// paramset with the key
+func (d *Device) GetParamsetDescription(key string) (map[string]ParameterDescription, error) {
+	return d.GetParamsetDescriptionContext(context.Background(), key)
+}
+
+// GetParamsetDescriptionContext returns the description of all parameters
+// of the paramset with the key and aborts if context is done
+func (d *Device) GetParamsetDescriptionContext(ctx context.Context, key string) (map[string]ParameterDescription, error) {
+	response, err := d.client.CallContext(ctx,
+		"getParamsetDescription",
+		[]interface{}{d.Address, key})
+	if err != nil {
+		return nil, err
+	}
+	if response.Fault != nil {
+		return nil, response.Fault
+	}
+
+	var rawData map[string]interface{}
+	err = rpc.Unmarshal(response.FirstParam(), &rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make(map[string]ParameterDescription, len(rawData))
+	for name, value := range rawData {
+		descriptions[name], err = loadParameterDescription(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return descriptions, nil
+}
+
+// checkParameters returns a *ParameterError if a parameter is not
+// in the descriptions
+func checkParameters(key string, descriptions map[string]ParameterDescription, parameters ...string) error {
+	var unknown []string
+	for _, parameter := range parameters {
+		if _, ok := descriptions[parameter]; !ok {
+			unknown = append(unknown, parameter)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &ParameterError{
+		Paramset:   key,
+		Parameters: unknown,
+	}
+}
+
+// GetMasterValue returns the value of a MASTER parameter
+func (d *Device) GetMasterValue(name string) (interface{}, error) {
+	return d.GetMasterValueContext(context.Background(), name)
+}
+
+// GetMasterValueContext returns the value of a MASTER parameter
+// and aborts if context is done
+func (d *Device) GetMasterValueContext(ctx context.Context, name string) (interface{}, error) {
+	values, err := d.GetParamsetContext(ctx, ParamsetMaster)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := values[name]
+	if !ok {
+		return nil, &ParameterError{
+			Paramset:   ParamsetMaster,
+			Parameters: []string{name},
+		}
+	}
+	return value, nil
+}
+
+// SetMasterValue changes the value of a MASTER parameter
+func (d *Device) SetMasterValue(name string, value interface{}) error {
+	return d.SetMasterValueContext(context.Background(), name, value)
+}
+
+// SetMasterValueContext changes the value of a MASTER parameter
+// and aborts if context is done
+func (d *Device) SetMasterValueContext(ctx context.Context, name string, value interface{}) error {
+	return d.PutParamsetContext(ctx, ParamsetMaster, map[string]interface{}{
+		name: value,
+	})
+}
+
+// TransmitAttempts returns the maximum number of transmit attempts
+func (d *Device) TransmitAttempts() (int, error) {
+	return d.TransmitAttemptsContext(context.Background())
+}
+
+// TransmitAttemptsContext returns the maximum number of transmit attempts
+// and aborts if context is done
+func (d *Device) TransmitAttemptsContext(ctx context.Context) (int, error) {
+	value, err := d.GetMasterValueContext(ctx, ParameterTransmitAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return cast.ToIntE(value)
+}
+
+// SetTransmitAttempts changes the maximum number of transmit attempts
+func (d *Device) SetTransmitAttempts(attempts int) error {
+	return d.SetTransmitAttemptsContext(context.Background(), attempts)
+}
+
+// SetTransmitAttemptsContext changes the maximum number of transmit attempts
+// and aborts if context is done
+func (d *Device) SetTransmitAttemptsContext(ctx context.Context, attempts int) error {
+	return d.SetMasterValueContext(ctx, ParameterTransmitAttempts, attempts)
+}
+
+// ButtonLock returns true if the buttons of the device are locked
+func (d *Device) ButtonLock() (bool, error) {
+	return d.ButtonLockContext(context.Background())
+}
+
+// ButtonLockContext returns true if the buttons of the device are locked
+// and aborts if context is done
+func (d *Device) ButtonLockContext(ctx context.Context) (bool, error) {
+	value, err := d.GetMasterValueContext(ctx, ParameterButtonLock)
+	if err != nil {
+		return false, err
+	}
+	return cast.ToBoolE(value)
+}
+
+// SetButtonLock locks or unlocks the buttons of the device
+func (d *Device) SetButtonLock(locked bool) error {
+	return d.SetButtonLockContext(context.Background(), locked)
+}
+
+// SetButtonLockContext locks or unlocks the buttons of the device
+// and aborts if context is done
+func (d *Device) SetButtonLockContext(ctx context.Context, locked bool) error {
+	return d.SetMasterValueContext(ctx, ParameterButtonLock, locked)
+}
+
+// TemperatureOffset returns the temperature offset of a thermostat in K
+func (d *Device) TemperatureOffset() (float64, error) {
+	return d.TemperatureOffsetContext(context.Background())
+}
+
+// TemperatureOffsetContext returns the temperature offset of a thermostat in K
+// and aborts if context is done
+// -> entries of value lists (e.g. "+1.5K") are converted to the offset
+func (d *Device) TemperatureOffsetContext(ctx context.Context) (float64, error) {
+	value, err := d.GetMasterValueContext(ctx, ParameterTemperatureOffset)
+	if err != nil {
+		return 0, err
+	}
+
+	descriptions, err := d.GetParamsetDescriptionContext(ctx, ParamsetMaster)
+	if err != nil {
+		return 0, err
+	}
+	valueList := descriptions[ParameterTemperatureOffset].ValueList
+	if len(valueList) == 0 {
+		return cast.ToFloat64E(value)
+	}
+
+	index, err := cast.ToIntE(value)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= len(valueList) {
+		return 0, fmt.Errorf("invalid temperature offset index %d", index)
+	}
+	return parseOffset(valueList[index])
+}
+
+// SetTemperatureOffset changes the temperature offset of a thermostat in K
+func (d *Device) SetTemperatureOffset(offset float64) error {
+	return d.SetTemperatureOffsetContext(context.Background(), offset)
+}
+
+// SetTemperatureOffsetContext changes the temperature offset of a thermostat in K
+// and aborts if context is done
+// -> devices with value lists are set to the nearest entry
+func (d *Device) SetTemperatureOffsetContext(ctx context.Context, offset float64) error {
+	descriptions, err := d.GetParamsetDescriptionContext(ctx, ParamsetMaster)
+	if err != nil {
+		return err
+	}
+	err = checkParameters(ParamsetMaster, descriptions, ParameterTemperatureOffset)
+	if err != nil {
+		return err
+	}
+
+	valueList := descriptions[ParameterTemperatureOffset].ValueList
+	if len(valueList) == 0 {
+		return d.SetMasterValueContext(ctx, ParameterTemperatureOffset, offset)
+	}
+
+	// select nearest entry of value list
+	index, distance := -1, math.Inf(1)
+	for i, entry := range valueList {
+		value, err := parseOffset(entry)
+		if err != nil {
+			continue
+		}
+		if math.Abs(value-offset) < distance {
+			index, distance = i, math.Abs(value-offset)
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("invalid temperature offset %g", offset)
+	}
+	return d.SetMasterValueContext(ctx, ParameterTemperatureOffset, index)
+}
+
+// parseOffset parses an entry of a value list like "-1.5K"
+func parseOffset(entry string) (float64, error) {
+	entry = strings.TrimSpace(entry)
+	entry = strings.TrimRight(entry, "K°C ")
+	return strconv.ParseFloat(entry, 64)
+}
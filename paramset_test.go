@@ -0,0 +1,149 @@
+package homematic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// testParamsetClient simulates the paramsets of a device
+func testParamsetClient(ass *assert.Assertions, paramsets map[string]map[string]interface{},
+	descriptions map[string]map[string]interface{}) testRpcClient {
+
+	return func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("address", params[0])
+		key := params[1].(string)
+
+		switch method {
+		case "getParamset":
+			values, ok := paramsets[key]
+			if !ok {
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -3, String: "unknown paramset"},
+				}, nil
+			}
+			return &rpc.Response{Params: []interface{}{values}}, nil
+
+		case "putParamset":
+			for name, value := range params[2].(map[string]interface{}) {
+				paramsets[key][name] = value
+			}
+			return &rpc.Response{}, nil
+
+		case "getParamsetDescription":
+			return &rpc.Response{Params: []interface{}{descriptions[key]}}, nil
+		}
+		ass.Fail("unexpected method", method)
+		return nil, nil
+	}
+}
+
+func TestDevice_Paramset(t *testing.T) {
+	ass := assert.New(t)
+
+	paramsets := map[string]map[string]interface{}{
+		ParamsetMaster: {"TRANSMIT_TRY_MAX": int32(6)},
+	}
+	device := &Device{
+		Address:   "address",
+		ParamSets: []string{ParamsetMaster, ParamsetValues},
+		client: testParamsetClient(ass, paramsets, map[string]map[string]interface{}{
+			ParamsetMaster: {
+				"TRANSMIT_TRY_MAX": map[string]interface{}{
+					"ID":   "TRANSMIT_TRY_MAX",
+					"TYPE": "INTEGER",
+				},
+			},
+		}),
+	}
+	ass.True(device.HasParamset(ParamsetMaster))
+	ass.False(device.HasParamset(ParamsetLink))
+
+	values, err := device.GetParamset(ParamsetMaster)
+	ass.NoError(err)
+	ass.Equal(map[string]interface{}{"TRANSMIT_TRY_MAX": int32(6)}, values)
+
+	_, err = device.GetParamset(ParamsetLink)
+	ass.EqualError(err, "unknown paramset (-3)")
+
+	ass.NoError(device.PutParamset(ParamsetMaster, map[string]interface{}{
+		"TRANSMIT_TRY_MAX": 3,
+	}))
+	ass.Equal(3, paramsets[ParamsetMaster]["TRANSMIT_TRY_MAX"])
+
+	descriptions, err := device.GetParamsetDescription(ParamsetMaster)
+	ass.NoError(err)
+	ass.Equal("INTEGER", descriptions["TRANSMIT_TRY_MAX"].Type)
+}
+
+func TestDevice_masterHelpers(t *testing.T) {
+	ass := assert.New(t)
+
+	paramsets := map[string]map[string]interface{}{
+		ParamsetMaster: {
+			"TRANSMIT_TRY_MAX":   int32(6),
+			"BUTTON_LOCK":        false,
+			"TEMPERATURE_OFFSET": int32(7),
+		},
+	}
+	descriptions := map[string]map[string]interface{}{
+		ParamsetMaster: {
+			"TEMPERATURE_OFFSET": map[string]interface{}{
+				"TYPE": "ENUM",
+				"VALUE_LIST": []interface{}{
+					"-3.5K", "-3.0K", "-2.5K", "-2.0K", "-1.5K", "-1.0K", "-0.5K",
+					"0.0K", "0.5K", "1.0K", "1.5K", "2.0K", "2.5K", "3.0K", "3.5K",
+				},
+			},
+		},
+	}
+	device := &Device{
+		Address: "address",
+		client:  testParamsetClient(ass, paramsets, descriptions),
+	}
+
+	attempts, err := device.TransmitAttempts()
+	ass.NoError(err)
+	ass.Equal(6, attempts)
+	ass.NoError(device.SetTransmitAttempts(3))
+	attempts, err = device.TransmitAttempts()
+	ass.NoError(err)
+	ass.Equal(3, attempts)
+
+	locked, err := device.ButtonLock()
+	ass.NoError(err)
+	ass.False(locked)
+	ass.NoError(device.SetButtonLock(true))
+	locked, err = device.ButtonLock()
+	ass.NoError(err)
+	ass.True(locked)
+
+	// value list of HomeMatic RF thermostats
+	offset, err := device.TemperatureOffset()
+	ass.NoError(err)
+	ass.Equal(0.0, offset)
+	ass.NoError(device.SetTemperatureOffset(1.4))
+	ass.Equal(10, paramsets[ParamsetMaster]["TEMPERATURE_OFFSET"])
+	offset, err = device.TemperatureOffset()
+	ass.NoError(err)
+	ass.Equal(1.5, offset)
+
+	// float value of HomeMatic IP thermostats
+	descriptions[ParamsetMaster]["TEMPERATURE_OFFSET"] = map[string]interface{}{
+		"TYPE": "FLOAT",
+	}
+	ass.NoError(device.SetTemperatureOffset(-2))
+	offset, err = device.TemperatureOffset()
+	ass.NoError(err)
+	ass.Equal(-2.0, offset)
+
+	// parameter not supported by device
+	delete(paramsets[ParamsetMaster], "BUTTON_LOCK")
+	_, err = device.ButtonLock()
+	ass.EqualError(err, "invalid parameter BUTTON_LOCK in paramset MASTER")
+	delete(descriptions[ParamsetMaster], "TEMPERATURE_OFFSET")
+	err = device.SetTemperatureOffset(1)
+	ass.IsType(&ParameterError{}, err)
+}
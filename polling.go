@@ -0,0 +1,127 @@
+package homematic
+
+import (
+	"context"
+	"time"
+)
+
+// interval the poller checks which devices must be polled
+const pollTick = time.Second
+
+// PollingMode defines when the values of devices are polled
+type PollingMode int
+
+// modes of polling
+const (
+	// PollingAuto polls only interfaces without working callback connection
+	PollingAuto PollingMode = iota
+	// PollingAlways polls all interfaces in addition to the events
+	PollingAlways
+	// PollingDisabled never polls
+	PollingDisabled
+)
+
+// SetPollInterval sets the interval the values of the device are polled
+// -> 0 uses the interval of the interface (see WithPollInterval)
+// -> a negative interval disables polling of the device
+func (d *Device) SetPollInterval(interval time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pollInterval = interval
+}
+
+// getPollInterval returns the poll interval set for the device
+func (d *Device) getPollInterval() time.Duration {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.pollInterval
+}
+
+// polling returns true if the devices of the interface should be polled
+// Note: clientMutex must be held by caller
+func (c *CCU) polling(id string) bool {
+	status := c.interfaceStatus[id]
+	if !status.Available {
+		return false
+	}
+
+	switch c.options.pollingMode {
+	case PollingAlways:
+		return true
+	case PollingAuto:
+		switch status.State {
+		case ConnectionBroken:
+			return true
+		case ConnectionUnknown:
+			// ping not supported -> poll only if no events since some time
+			return time.Since(c.lastEvent(id)) >= c.options.eventTimeout
+		}
+	}
+	return false
+}
+
+// pollDevices loads the values of all devices with expired poll interval
+// on interfaces that are polled
+func (c *CCU) pollDevices(ctx context.Context) error {
+	c.clientMutex.RLock()
+	interfaces := make(map[string]bool, len(c.interfaceStatus))
+	var changed bool
+	for id, status := range c.interfaceStatus {
+		interfaces[id] = c.polling(id)
+		changed = changed || status.Polling != interfaces[id]
+	}
+	c.clientMutex.RUnlock()
+
+	// status is only written if polling changed
+	if changed {
+		c.clientMutex.Lock()
+		for id, polling := range interfaces {
+			status, ok := c.interfaceStatus[id]
+			if ok {
+				status.Polling = polling
+				c.interfaceStatus[id] = status
+			}
+		}
+		c.clientMutex.Unlock()
+	}
+
+	now := time.Now()
+	c.deviceMutex.RLock()
+	var devices []*Device
+	for address, device := range c.devices {
+		if !interfaces[device.Interface] || !device.HasValues() {
+			continue
+		}
+
+		interval := device.getPollInterval()
+		if interval == 0 {
+			interval = c.options.interfacePollInterval(c.interfaces[device.Interface].Name)
+		}
+		if interval <= 0 || now.Before(c.supervisor.nextPoll(address)) {
+			continue
+		}
+		c.supervisor.setNextPoll(address, now.Add(interval))
+		devices = append(devices, device)
+	}
+	c.deviceMutex.RUnlock()
+
+	return c.loadValues(ctx, devices)
+}
+
+// pollValues polls the values of the devices until the context is done
+func (c *CCU) pollValues(ctx context.Context) {
+	ticker := time.NewTicker(pollTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// failed devices are polled again after the next interval
+		_ = c.pollDevices(ctx)
+	}
+}
@@ -0,0 +1,105 @@
+package homematic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestCCU_polling(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	ccu.interfaceStatus["connected"] = InterfaceStatus{Available: true, State: ConnectionConnected}
+	ccu.interfaceStatus["broken"] = InterfaceStatus{Available: true, State: ConnectionBroken}
+	ccu.interfaceStatus["unreachable"] = InterfaceStatus{State: ConnectionBroken}
+	ccu.interfaceStatus["noping"] = InterfaceStatus{Available: true, State: ConnectionUnknown}
+	ccu.setLastEvent("noping", time.Now())
+
+	ass.False(ccu.polling("connected"))
+	ass.True(ccu.polling("broken"))
+	ass.False(ccu.polling("unreachable"))
+	ass.False(ccu.polling("noping"))
+
+	ccu.setLastEvent("noping", time.Time{})
+	ass.True(ccu.polling("noping"))
+
+	WithPolling(PollingAlways)(ccu.options)
+	ass.True(ccu.polling("connected"))
+	ass.False(ccu.polling("unreachable"))
+
+	WithPolling(PollingDisabled)(ccu.options)
+	ass.False(ccu.polling("broken"))
+}
+
+func TestCCU_pollDevices(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceRF, InterfaceHmIP),
+		WithPollInterval(time.Hour, "hmip"))
+	ass.NoError(err)
+
+	polled := make(map[string]int)
+	client := testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("getParamset", method)
+		polled[params[0].(string)]++
+		return &rpc.Response{
+			Params: []interface{}{map[string]interface{}{
+				"STATE": polled[params[0].(string)] > 1,
+			}},
+		}, nil
+	})
+	ccu.options.snapshotConcurrency = 1
+
+	ccu.interfaceStatus["go-rf"] = InterfaceStatus{Available: true, State: ConnectionBroken}
+	ccu.interfaceStatus["go-hmip"] = InterfaceStatus{Available: true, State: ConnectionBroken}
+	for address, id := range map[string]string{
+		"rf":       "go-rf",
+		"hmip":     "go-hmip",
+		"disabled": "go-rf",
+	} {
+		ccu.devices[address] = &Device{
+			Address:   address,
+			Interface: id,
+			ParamSets: []string{"VALUES"},
+			client:    client,
+		}
+	}
+	ccu.devices["disabled"].SetPollInterval(-1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := ccu.Events(ctx, EventFilter{})
+
+	ass.NoError(ccu.pollDevices(context.Background()))
+	ass.Equal(map[string]int{"rf": 1, "hmip": 1}, polled)
+	ass.Len(events, 0)
+	ass.True(ccu.Interfaces()["go-rf"].Polling)
+
+	// poll only after interval
+	ccu.supervisor.setNextPoll("rf", time.Now())
+	ass.NoError(ccu.pollDevices(context.Background()))
+	ass.Equal(map[string]int{"rf": 2, "hmip": 1}, polled)
+	ass.Len(events, 1)
+
+	// connection is back -> polling stops
+	ccu.interfaceStatus["go-rf"] = InterfaceStatus{Available: true, State: ConnectionConnected}
+	ccu.supervisor.setNextPoll("rf", time.Now())
+	ass.NoError(ccu.pollDevices(context.Background()))
+	ass.Equal(map[string]int{"rf": 2, "hmip": 1}, polled)
+	ass.False(ccu.Interfaces()["go-rf"].Polling)
+
+	// poll time of removed devices is removed
+	ass.Contains(ccu.supervisor.polls, "hmip")
+	ccu.deviceMutex.Lock()
+	ccu.removeDevice("hmip")
+	ccu.deviceMutex.Unlock()
+	ass.NotContains(ccu.supervisor.polls, "hmip")
+}
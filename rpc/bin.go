@@ -0,0 +1,469 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// magic bytes of each binary RPC message
+var binMagic = []byte("Bin")
+
+// message types of binary RPC
+const (
+	binMessageRequest        = 0x00
+	binMessageResponse       = 0x01
+	binMessageRequestHeader  = 0x40
+	binMessageResponseHeader = 0x41
+	binMessageFault          = 0xff
+)
+
+// maximum size of the body of a binary RPC message
+// -> prevents huge allocations on invalid data
+const binMaxMessageSize = 64 << 20
+
+// value types of binary RPC
+const (
+	binTypeInteger = 0x01
+	binTypeBoolean = 0x02
+	binTypeString  = 0x03
+	binTypeDouble  = 0x04
+	binTypeBase64  = 0x11
+	binTypeInt64   = 0xD1
+	binTypeArray   = 0x100
+	binTypeStruct  = 0x101
+)
+
+// MarshalBinary convert request to binary RPC data
+func (r Request) MarshalBinary() ([]byte, error) {
+	return r.marshalBinary(nil)
+}
+
+// marshalBinary convert request with optional headers to binary RPC data
+func (r Request) marshalBinary(headers map[string]string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	writeBinString(buf, r.Method)
+	writeBinUint32(buf, uint32(len(r.Params)))
+	for _, p := range r.Params {
+		err := encodeBinValue(p, buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(headers) == 0 {
+		return binMessage(binMessageRequest, buf.Bytes()), nil
+	}
+
+	// header block is placed in front of the request body
+	header := new(bytes.Buffer)
+	writeBinUint32(header, uint32(len(headers)))
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeBinString(header, key)
+		writeBinString(header, headers[key])
+	}
+	writeBinUint32(header, uint32(buf.Len()))
+	header.Write(buf.Bytes())
+
+	// length in message header only covers the header block
+	data := binMessage(binMessageRequestHeader, header.Bytes())
+	binary.BigEndian.PutUint32(data[4:], uint32(header.Len()-buf.Len()-4))
+	return data, nil
+}
+
+// ParseBinaryRequest from binary RPC data
+func ParseBinaryRequest(reader io.Reader) (*Request, error) {
+	messageType, data, err := readBinMessage(reader)
+	if err != nil {
+		return nil, err
+	}
+	if messageType != binMessageRequest {
+		return nil, fmt.Errorf("invalid request message type %d", messageType)
+	}
+
+	d := &binDecoder{data: data}
+	method, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	count, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &Request{
+		Method: method,
+		Params: make([]interface{}, 0, d.capacity(count)),
+	}
+	for i := uint32(0); i < count; i++ {
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		request.Params = append(request.Params, value)
+	}
+	return request, nil
+}
+
+// MarshalBinary convert response to binary RPC data
+func (r *Response) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if r.Fault != nil {
+		err := encodeBinValue(r.Fault.toMap(), buf)
+		if err != nil {
+			return nil, err
+		}
+		return binMessage(binMessageFault, buf.Bytes()), nil
+	}
+
+	// binary responses contain exactly one value
+	var value interface{}
+	switch len(r.Params) {
+	case 0:
+		value = ""
+	case 1:
+		value = r.Params[0]
+	default:
+		value = r.Params
+	}
+	err := encodeBinValue(value, buf)
+	if err != nil {
+		return nil, err
+	}
+	return binMessage(binMessageResponse, buf.Bytes()), nil
+}
+
+// ParseBinaryResponse from binary RPC data
+func ParseBinaryResponse(reader io.Reader) (*Response, error) {
+	messageType, data, err := readBinMessage(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &binDecoder{data: data}
+	switch messageType {
+	case binMessageResponse:
+		// empty responses are allowed
+		if len(data) == 0 {
+			return &Response{}, nil
+		}
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		return &Response{
+			Params: []interface{}{value},
+		}, nil
+
+	case binMessageFault:
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid fault value")
+		}
+		code, _ := data["faultCode"].(int32)
+		str, _ := data["faultString"].(string)
+		return &Response{
+			Fault: &Fault{
+				Code:   code,
+				String: str,
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid response message type %d", messageType)
+	}
+}
+
+// binMessage creates a binary RPC message with the given type and body
+func binMessage(messageType byte, body []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(body)+8))
+	buf.Write(binMagic)
+	buf.WriteByte(messageType)
+	writeBinUint32(buf, uint32(len(body)))
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// readBinMessage reads a binary RPC message and returns its type and body
+func readBinMessage(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 8)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !bytes.Equal(header[:3], binMagic) {
+		return 0, nil, errors.New("invalid binary RPC message")
+	}
+	messageType := header[3]
+	length := binary.BigEndian.Uint32(header[4:])
+
+	// skip header data if present
+	if messageType == binMessageRequestHeader || messageType == binMessageResponseHeader {
+		_, err = io.CopyN(ioutil.Discard, reader, int64(length))
+		if err != nil {
+			return 0, nil, err
+		}
+		_, err = io.ReadFull(reader, header[4:])
+		if err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint32(header[4:])
+		messageType &^= binMessageRequestHeader
+	}
+
+	if length > binMaxMessageSize {
+		return 0, nil, fmt.Errorf("binary RPC message too large (%d bytes)", length)
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(reader, data)
+	if err != nil {
+		return 0, nil, err
+	}
+	return messageType, data, nil
+}
+
+// writeBinUint32 writes a big endian uint32
+func writeBinUint32(buf *bytes.Buffer, value uint32) {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], value)
+	buf.Write(data[:])
+}
+
+// writeBinString writes a length prefixed string
+func writeBinString(buf *bytes.Buffer, value string) {
+	writeBinUint32(buf, uint32(len(value)))
+	buf.WriteString(value)
+}
+
+// encodeBinValue convert value to binary RPC data
+func encodeBinValue(value interface{}, buf *bytes.Buffer) error {
+	switch v := value.(type) {
+	case string:
+		writeBinUint32(buf, binTypeString)
+		writeBinString(buf, v)
+
+	case nil:
+		// binary RPC has no nil type -> use empty string
+		writeBinUint32(buf, binTypeString)
+		writeBinString(buf, "")
+
+	case bool:
+		writeBinUint32(buf, binTypeBoolean)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+
+	case float32:
+		return encodeBinValue(float64(v), buf)
+	case float64:
+		writeBinUint32(buf, binTypeDouble)
+		// mantissa is stored with 30 bit precision
+		frac, exp := math.Frexp(v)
+		writeBinUint32(buf, uint32(int32(math.Round(frac*0x40000000))))
+		writeBinUint32(buf, uint32(int32(exp)))
+
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		i, err := integerValue(v)
+		if err != nil {
+			return err
+		}
+
+		// use 64 bit type only if value exceeds 32 bit
+		if i > math.MaxInt32 || i < math.MinInt32 {
+			writeBinUint32(buf, binTypeInt64)
+			var data [8]byte
+			binary.BigEndian.PutUint64(data[:], uint64(i))
+			buf.Write(data[:])
+		} else {
+			writeBinUint32(buf, binTypeInteger)
+			writeBinUint32(buf, uint32(i))
+		}
+
+	case []byte:
+		writeBinUint32(buf, binTypeBase64)
+		writeBinString(buf, base64.StdEncoding.EncodeToString(v))
+
+	case []interface{}:
+		writeBinUint32(buf, binTypeArray)
+		writeBinUint32(buf, uint32(len(v)))
+		for _, entry := range v {
+			err := encodeBinValue(entry, buf)
+			if err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		writeBinUint32(buf, binTypeStruct)
+		writeBinUint32(buf, uint32(len(v)))
+
+		// sort keys for a stable output
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			writeBinString(buf, key)
+			err := encodeBinValue(v[key], buf)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		return errors.New("unknown value type")
+	}
+	return nil
+}
+
+// binDecoder reads values from binary RPC data
+type binDecoder struct {
+	data []byte
+	pos  int
+}
+
+// read the given number of bytes
+func (d *binDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return data, nil
+}
+
+// capacity limits the given entry count to the remaining data
+// -> prevents huge allocations on invalid data
+func (d *binDecoder) capacity(count uint32) int {
+	remaining := (len(d.data) - d.pos) / 4
+	if int(count) > remaining {
+		return remaining
+	}
+	return int(count)
+}
+
+// readUint32 reads a big endian uint32
+func (d *binDecoder) readUint32() (uint32, error) {
+	data, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(data), nil
+}
+
+// readString reads a length prefixed string
+func (d *binDecoder) readString() (string, error) {
+	length, err := d.readUint32()
+	if err != nil {
+		return "", err
+	}
+	data, err := d.read(int(length))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readValue reads a typed value
+func (d *binDecoder) readValue() (interface{}, error) {
+	valueType, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	switch valueType {
+	case binTypeInteger:
+		value, err := d.readUint32()
+		return int32(value), err
+
+	case binTypeInt64:
+		data, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(binary.BigEndian.Uint64(data)), nil
+
+	case binTypeBoolean:
+		data, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return data[0] != 0, nil
+
+	case binTypeString:
+		return d.readString()
+
+	case binTypeBase64:
+		str, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(str)
+
+	case binTypeDouble:
+		mantissa, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		return math.Ldexp(float64(int32(mantissa))/0x40000000, int(int32(exponent))), nil
+
+	case binTypeArray:
+		count, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, d.capacity(count))
+		for i := uint32(0); i < count; i++ {
+			value, err := d.readValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+
+	case binTypeStruct:
+		count, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, d.capacity(count))
+		for i := uint32(0); i < count; i++ {
+			key, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			values[key], err = d.readValue()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	default:
+		return nil, fmt.Errorf("invalid value type 0x%x", valueType)
+	}
+}
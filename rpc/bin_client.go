@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"time"
+)
+
+// NewBinClient creates new client for the binary RPC protocol
+// (e.g. xmlrpc_bin://192.168.4.40:2001)
+func NewBinClient(url string, options ...ClientOption) Client {
+	o := newClientOptions(options)
+	return &binClient{
+		URL:     url,
+		options: o,
+		timeout: o.timeout,
+	}
+}
+
+// binary RPC client
+type binClient struct {
+	URL     string
+	options *clientOptions
+	timeout time.Duration
+}
+
+// Call sends an RPC to server with the default timeout
+func (c *binClient) Call(method string, params []interface{}) (*Response, error) {
+	return c.CallContext(context.Background(), method, params)
+}
+
+// CallContext sends an RPC to server and aborts if the context is done
+// (default timeout is used if context has no deadline)
+func (c *binClient) CallContext(ctx context.Context, method string, params []interface{}) (*Response, error) {
+	// use default timeout if context has no deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var headers map[string]string
+	if c.options.hasAuth() {
+		headers = map[string]string{
+			"Authorization": "Basic " + base64.StdEncoding.EncodeToString(
+				[]byte(c.options.username+":"+c.options.password)),
+		}
+	}
+	data, err := Request{
+		Method: method,
+		Params: params,
+	}.marshalBinary(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := urlHost(c.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.options.tlsConfig != nil {
+		config := c.options.tlsConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(host)
+		}
+		conn = tls.Client(conn, config)
+		defer conn.Close()
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// close connection if context is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	_, err = conn.Write(data)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	response, err := ParseBinaryResponse(conn)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return response, nil
+}
+
+// contextError returns the error of the context if it is done
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// LocalIP returns the IP used to reach the server
+func (c *binClient) LocalIP() (string, error) {
+	return localIP(c.URL)
+}
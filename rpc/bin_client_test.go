@@ -0,0 +1,153 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinClient_Call(t *testing.T) {
+	ass := assert.New(t)
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	ass.NoError(err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		request, err := ParseBinaryRequest(conn)
+		ass.NoError(err)
+		ass.Equal(&Request{
+			Method: "test",
+			Params: []interface{}{"42"},
+		}, request)
+
+		data, err := (&Response{Params: []interface{}{42}}).MarshalBinary()
+		ass.NoError(err)
+		_, err = conn.Write(data)
+		ass.NoError(err)
+	}()
+
+	c := NewBinClient("xmlrpc_bin://" + listener.Addr().String())
+
+	response, err := c.Call("test", []interface{}{"42"})
+	ass.NoError(err)
+	ass.Equal(int32(42), response.FirstParam())
+
+	ip, err := c.LocalIP()
+	ass.NoError(err)
+	ass.Equal("127.0.0.1", ip)
+
+	_, err = c.Call("test", []interface{}{struct{}{}})
+	ass.EqualError(err, "unknown value type")
+}
+
+func TestBinClient_CallContext(t *testing.T) {
+	ass := assert.New(t)
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	ass.NoError(err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// never respond
+		_, _ = ParseBinaryRequest(conn)
+		time.Sleep(time.Millisecond * 100)
+	}()
+
+	c := NewBinClient("xmlrpc_bin://" + listener.Addr().String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		cancel()
+	}()
+
+	_, err = c.CallContext(ctx, "test", nil)
+	ass.Equal(context.Canceled, err)
+}
+
+func TestBinClient_TLS(t *testing.T) {
+	ass := assert.New(t)
+
+	// use certificate of test server
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+
+	listener, err := tls.Listen("tcp4", "127.0.0.1:0", server.TLS)
+	ass.NoError(err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// request with authorization header
+		header := make([]byte, 8)
+		_, err = io.ReadFull(conn, header)
+		ass.NoError(err)
+		ass.Equal([]byte("Bin\x40"), header[:4])
+
+		headerData := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		_, err = io.ReadFull(conn, headerData)
+		ass.NoError(err)
+		decoder := &binDecoder{data: headerData}
+		count, err := decoder.readUint32()
+		ass.NoError(err)
+		ass.Equal(uint32(1), count)
+		key, err := decoder.readString()
+		ass.NoError(err)
+		ass.Equal("Authorization", key)
+		value, err := decoder.readString()
+		ass.NoError(err)
+		ass.Equal("Basic dXNlcjpwYXNz", value)
+
+		body := make([]byte, 4)
+		_, err = io.ReadFull(conn, body)
+		ass.NoError(err)
+		body = make([]byte, binary.BigEndian.Uint32(body))
+		_, err = io.ReadFull(conn, body)
+		ass.NoError(err)
+		decoder = &binDecoder{data: body}
+		method, err := decoder.readString()
+		ass.NoError(err)
+		ass.Equal("test", method)
+
+		data, err := (&Response{Params: []interface{}{42}}).MarshalBinary()
+		ass.NoError(err)
+		_, err = conn.Write(data)
+		ass.NoError(err)
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	c := NewBinClient("xmlrpc_bin://"+listener.Addr().String(),
+		WithTLSConfig(&tls.Config{RootCAs: pool}),
+		WithBasicAuth("user", "pass"))
+
+	response, err := c.Call("test", nil)
+	ass.NoError(err)
+	ass.Equal(int32(42), response.FirstParam())
+}
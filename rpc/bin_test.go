@@ -0,0 +1,279 @@
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest_MarshalBinary(t *testing.T) {
+	ass := assert.New(t)
+
+	data, err := Request{
+		Method: "init",
+		Params: []interface{}{
+			"xmlrpc_bin://127.0.0.1:1234",
+			"id",
+		},
+	}.MarshalBinary()
+	ass.NoError(err)
+	ass.Equal([]byte("Bin\x00\x00\x00\x00\x39"+
+		"\x00\x00\x00\x04init"+
+		"\x00\x00\x00\x02"+
+		"\x00\x00\x00\x03\x00\x00\x00\x1bxmlrpc_bin://127.0.0.1:1234"+
+		"\x00\x00\x00\x03\x00\x00\x00\x02id"), data)
+
+	request, err := ParseBinaryRequest(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&Request{
+		Method: "init",
+		Params: []interface{}{
+			"xmlrpc_bin://127.0.0.1:1234",
+			"id",
+		},
+	}, request)
+
+	_, err = Request{
+		Method: "test",
+		Params: []interface{}{struct{}{}},
+	}.MarshalBinary()
+	ass.EqualError(err, "unknown value type")
+}
+
+func TestParseBinaryRequest(t *testing.T) {
+	ass := assert.New(t)
+
+	_, err := ParseBinaryRequest(bytes.NewReader([]byte("Bin")))
+	ass.Equal(io.ErrUnexpectedEOF, err)
+
+	_, err = ParseBinaryRequest(bytes.NewReader([]byte("Xyz\x00\x00\x00\x00\x00")))
+	ass.EqualError(err, "invalid binary RPC message")
+
+	_, err = ParseBinaryRequest(bytes.NewReader([]byte("Bin\x01\x00\x00\x00\x00")))
+	ass.EqualError(err, "invalid request message type 1")
+
+	_, err = ParseBinaryRequest(bytes.NewReader([]byte("Bin\x00\x00\x00\x00\x02\x00\x00")))
+	ass.Equal(io.ErrUnexpectedEOF, err)
+
+	// length is not trusted
+	_, err = ParseBinaryRequest(bytes.NewReader([]byte("Bin\x00\xff\xff\xff\xff")))
+	ass.EqualError(err, "binary RPC message too large (4294967295 bytes)")
+
+	// request with header
+	request, err := ParseBinaryRequest(bytes.NewReader([]byte(
+		"Bin\x40\x00\x00\x00\x02ab" +
+			"\x00\x00\x00\x0c\x00\x00\x00\x04ping\x00\x00\x00\x00")))
+	ass.NoError(err)
+	ass.Equal(&Request{
+		Method: "ping",
+		Params: []interface{}{},
+	}, request)
+}
+
+func TestResponse_MarshalBinary(t *testing.T) {
+	ass := assert.New(t)
+
+	data, err := (&Response{}).MarshalBinary()
+	ass.NoError(err)
+	ass.Equal([]byte("Bin\x01\x00\x00\x00\x08\x00\x00\x00\x03\x00\x00\x00\x00"), data)
+
+	data, err = (&Response{Params: []interface{}{nil}}).MarshalBinary()
+	ass.NoError(err)
+	ass.Equal([]byte("Bin\x01\x00\x00\x00\x08\x00\x00\x00\x03\x00\x00\x00\x00"), data)
+
+	data, err = (&Response{Params: []interface{}{true}}).MarshalBinary()
+	ass.NoError(err)
+	ass.Equal([]byte("Bin\x01\x00\x00\x00\x05\x00\x00\x00\x02\x01"), data)
+
+	response, err := ParseBinaryResponse(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&Response{Params: []interface{}{true}}, response)
+
+	data, err = (&Response{Params: []interface{}{1, "a"}}).MarshalBinary()
+	ass.NoError(err)
+	response, err = ParseBinaryResponse(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&Response{Params: []interface{}{
+		[]interface{}{int32(1), "a"},
+	}}, response)
+
+	data, err = (&Response{Fault: &Fault{
+		Code:   -2,
+		String: "Unknown instance",
+	}}).MarshalBinary()
+	ass.NoError(err)
+	response, err = ParseBinaryResponse(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&Response{Fault: &Fault{
+		Code:   -2,
+		String: "Unknown instance",
+	}}, response)
+
+	_, err = (&Response{Params: []interface{}{struct{}{}}}).MarshalBinary()
+	ass.EqualError(err, "unknown value type")
+}
+
+func TestParseBinaryResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	response, err := ParseBinaryResponse(bytes.NewReader([]byte("Bin\x01\x00\x00\x00\x00")))
+	ass.NoError(err)
+	ass.Equal(&Response{}, response)
+
+	_, err = ParseBinaryResponse(bytes.NewReader([]byte("Bin\x00\x00\x00\x00\x00")))
+	ass.EqualError(err, "invalid response message type 0")
+
+	_, err = ParseBinaryResponse(bytes.NewReader([]byte("Bin\xff\x00\x00\x00\x08\x00\x00\x00\x03\x00\x00\x00\x00")))
+	ass.EqualError(err, "invalid fault value")
+
+	_, err = ParseBinaryResponse(bytes.NewReader([]byte("Bin\x01\x00\x00\x00\x04\x00\x00\x00\x09")))
+	ass.EqualError(err, "invalid value type 0x9")
+}
+
+var binValueTestData = []struct {
+	name  string
+	value interface{}
+	data  string
+	err   error
+}{{
+	"string",
+	"test",
+	"\x00\x00\x00\x03\x00\x00\x00\x04test",
+	nil,
+}, {
+	"integer",
+	int32(-2),
+	"\x00\x00\x00\x01\xff\xff\xff\xfe",
+	nil,
+}, {
+	"boolean",
+	false,
+	"\x00\x00\x00\x02\x00",
+	nil,
+}, {
+	"double",
+	float64(0.5),
+	"\x00\x00\x00\x04\x20\x00\x00\x00\x00\x00\x00\x00",
+	nil,
+}, {
+	"double_negative",
+	float64(-21.5),
+	"\x00\x00\x00\x04\xd5\x00\x00\x00\x00\x00\x00\x05",
+	nil,
+}, {
+	"double_zero",
+	float64(0),
+	"\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00",
+	nil,
+}, {
+	"int64",
+	int64(-1 << 40),
+	"\x00\x00\x00\xd1\xff\xff\xff\x00\x00\x00\x00\x00",
+	nil,
+}, {
+	"base64",
+	[]byte("test"),
+	"\x00\x00\x00\x11\x00\x00\x00\x08dGVzdA==",
+	nil,
+}, {
+	"array",
+	[]interface{}{int32(1), "a"},
+	"\x00\x00\x01\x00\x00\x00\x00\x02" +
+		"\x00\x00\x00\x01\x00\x00\x00\x01" +
+		"\x00\x00\x00\x03\x00\x00\x00\x01a",
+	nil,
+}, {
+	"struct",
+	map[string]interface{}{
+		"a": true,
+		"b": int32(2),
+	},
+	"\x00\x00\x01\x01\x00\x00\x00\x02" +
+		"\x00\x00\x00\x01a\x00\x00\x00\x02\x01" +
+		"\x00\x00\x00\x01b\x00\x00\x00\x01\x00\x00\x00\x02",
+	nil,
+}, {
+	"invalid_type",
+	nil,
+	"\x00\x00\x00\x42",
+	errors.New("invalid value type 0x42"),
+}, {
+	"truncated_array",
+	nil,
+	"\x00\x00\x01\x00\x00\x00\x00\x02\x00\x00\x00\x02\x01",
+	io.ErrUnexpectedEOF,
+}, {
+	"truncated_struct",
+	nil,
+	"\x00\x00\x01\x01\x00\x00\x00\x01\x00\x00\x00\x05a",
+	io.ErrUnexpectedEOF,
+}}
+
+func TestBinValue(t *testing.T) {
+	for _, d := range binValueTestData {
+		t.Run(d.name, func(st *testing.T) {
+			ass := assert.New(st)
+
+			decoder := &binDecoder{data: []byte(d.data)}
+			value, err := decoder.readValue()
+			ass.Equal(d.err, err)
+			ass.Equal(d.value, value)
+
+			if d.err == nil {
+				buf := new(bytes.Buffer)
+				ass.NoError(encodeBinValue(d.value, buf))
+				ass.Equal([]byte(d.data), buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestEncodeBinValue(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, value := range []interface{}{
+		int(1), int8(1), int16(1), int32(1), int64(1),
+		uint(1), uint8(1), uint16(1), uint32(1), uint64(1),
+	} {
+		buf := new(bytes.Buffer)
+		ass.NoError(encodeBinValue(value, buf))
+		ass.Equal([]byte("\x00\x00\x00\x01\x00\x00\x00\x01"), buf.Bytes())
+	}
+
+	// values beyond 32 bit use 64 bit type
+	buf := new(bytes.Buffer)
+	ass.NoError(encodeBinValue(uint64(1<<40), buf))
+	ass.Equal([]byte("\x00\x00\x00\xd1\x00\x00\x01\x00\x00\x00\x00\x00"), buf.Bytes())
+
+	ass.EqualError(encodeBinValue(uint64(1<<63), new(bytes.Buffer)),
+		"integer value out of range")
+
+	// doubles are stored with 30 bit precision
+	buf = new(bytes.Buffer)
+	ass.NoError(encodeBinValue(float32(1.2), buf))
+	value, err := (&binDecoder{data: buf.Bytes()}).readValue()
+	ass.NoError(err)
+	ass.InDelta(1.2, value, 0.000001)
+}
+
+func TestRequest_marshalBinary_headers(t *testing.T) {
+	ass := assert.New(t)
+
+	data, err := Request{
+		Method: "ping",
+	}.marshalBinary(map[string]string{
+		"Authorization": "Basic dXNlcjpwYXNz",
+	})
+	ass.NoError(err)
+
+	// header is skipped by parser
+	request, err := ParseBinaryRequest(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&Request{
+		Method: "ping",
+		Params: []interface{}{},
+	}, request)
+}
@@ -2,35 +2,57 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
 // Client interface for XML RPC client
 type Client interface {
 	Call(method string, params []interface{}) (*Response, error)
+	CallContext(ctx context.Context, method string, params []interface{}) (*Response, error)
 	LocalIP() (string, error)
 }
 
 // NewClient creates new client
-func NewClient(url string) Client {
-	return &client{url, &http.Client{
-		Timeout: time.Second * 5,
-	}}
+func NewClient(url string, options ...ClientOption) Client {
+	o := newClientOptions(options)
+	return &client{
+		URL:     url,
+		client:  o.httpClient(),
+		options: o,
+		timeout: o.timeout,
+	}
 }
 
 // RPC client
 type client struct {
-	URL    string
-	client *http.Client
+	URL     string
+	client  *http.Client
+	options *clientOptions
+	timeout time.Duration
 }
 
-// Call sends an RPC to server
+// Call sends an RPC to server with the default timeout
 func (c *client) Call(method string, params []interface{}) (*Response, error) {
+	return c.CallContext(context.Background(), method, params)
+}
+
+// CallContext sends an RPC to server and aborts if the context is done
+// (default timeout is used if context has no deadline)
+func (c *client) CallContext(ctx context.Context, method string, params []interface{}) (*Response, error) {
+	// use default timeout if context has no deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
 	buf := new(bytes.Buffer)
 	err := xml.NewEncoder(buf).Encode(Request{
 		Method: method,
@@ -40,24 +62,42 @@ func (c *client) Call(method string, params []interface{}) (*Response, error) {
 		return nil, err
 	}
 
-	resp, err := c.client.Post(c.URL, "text/xml", buf)
+	req, err := http.NewRequest(http.MethodPost, c.URL, buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if c.options.hasAuth() {
+		req.SetBasicAuth(c.options.username, c.options.password)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("authentication failed")
+	}
 	return ParseResponse(resp.Body)
 }
 
+// LocalIP returns the IP used to reach the server
 func (c *client) LocalIP() (string, error) {
+	return localIP(c.URL)
+}
+
+// localIP returns the local IP used to connect to the host of the URL
+func localIP(rawURL string) (string, error) {
 	// get host from url
-	u, err := url.Parse(c.URL)
+	host, err := urlHost(rawURL)
 	if err != nil {
 		return "", err
 	}
 
 	// create TCP connection
-	conn, err := net.Dial("tcp", u.Host)
+	conn, err := net.Dial("tcp", host)
 	if err != nil {
 		return "", err
 	}
@@ -71,3 +111,18 @@ func (c *client) LocalIP() (string, error) {
 	}
 	return tcpAddr.IP.String(), nil
 }
+
+// urlHost returns the host of the URL
+// -> url.Parse does not accept schemes like xmlrpc_bin
+func urlHost(rawURL string) (string, error) {
+	idx := strings.Index(rawURL, "://")
+	if idx < 0 {
+		return "", errors.New("invalid URL")
+	}
+
+	u, err := url.Parse("http" + rawURL[idx:])
+	if err != nil {
+		return "", err
+	}
+	return u.Host, nil
+}
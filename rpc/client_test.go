@@ -1,10 +1,14 @@
 package rpc
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -43,3 +47,58 @@ func TestClient_Call(t *testing.T) {
 	ass.NoError(err)
 	ass.Equal("127.0.0.1", ip)
 }
+
+func TestClient_CallContext(t *testing.T) {
+	ass := assert.New(t)
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// block until test is done
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := NewClient(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := c.CallContext(ctx, "test", nil)
+	ass.Error(err)
+	ass.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func TestClient_TLS(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		if !ok || username != "user" || password != "pass" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, err := rw.Write([]byte(`<methodResponse><params><param><value><i4>42</i4></value></param></params></methodResponse>`))
+		ass.NoError(err)
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	c := NewClient(server.URL, WithTLSConfig(&tls.Config{RootCAs: pool}))
+	_, err := c.Call("test", nil)
+	ass.EqualError(err, "authentication failed")
+
+	c = NewClient(server.URL,
+		WithTLSConfig(&tls.Config{RootCAs: pool}),
+		WithBasicAuth("user", "pass"))
+	response, err := c.Call("test", nil)
+	ass.NoError(err)
+	ass.Equal(int32(42), response.FirstParam())
+
+	// unknown CA
+	c = NewClient(server.URL, WithTLSConfig(new(tls.Config)))
+	_, err = c.Call("test", nil)
+	ass.Error(err)
+}
@@ -0,0 +1,322 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// tag name of struct fields
+// (e.g. `xmlrpc:"ADDRESS"` or `xmlrpc:"PARENT,omitempty"`)
+const tagName = "xmlrpc"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Marshal converts a go value to a value usable as RPC parameter
+// (structs are converted to maps with the field names from xmlrpc tags)
+func Marshal(v interface{}) (interface{}, error) {
+	return marshalValue(reflect.ValueOf(v))
+}
+
+// marshalValue converts the reflected value
+func marshalValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalValue(v.Elem())
+
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, errors.New("integer value out of range")
+		}
+		return int64(v.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		// byte slices are handled as base64
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return data, nil
+		}
+
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			value, err := marshalValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		values := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			value, err := marshalValue(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			values[key.String()] = value
+		}
+		return values, nil
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface(), nil
+		}
+
+		values := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name, omitEmpty, ok := fieldInfo(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+
+			field := v.Field(i)
+			if omitEmpty && isEmptyValue(field) {
+				continue
+			}
+
+			value, err := marshalValue(field)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = value
+		}
+		return values, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// Unmarshal converts a received RPC value into the value pointed to by target
+// (maps are converted to structs with the field names from xmlrpc tags)
+func Unmarshal(value interface{}, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("target must be a non-nil pointer")
+	}
+	return unmarshalValue(value, v.Elem(), "")
+}
+
+// unmarshalValue stores the value in the reflected target
+func unmarshalValue(value interface{}, v reflect.Value, path string) error {
+	// nil values result in the zero value
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return unmarshalError(value, v, path)
+		}
+		v.Set(reflect.ValueOf(value))
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(value, v.Elem(), path)
+
+	case reflect.String:
+		switch value.(type) {
+		case []interface{}, map[string]interface{}:
+			return unmarshalError(value, v, path)
+		}
+		str, err := cast.ToStringE(value)
+		if err != nil {
+			return unmarshalError(value, v, path)
+		}
+		v.SetString(str)
+
+	case reflect.Bool:
+		b, err := cast.ToBoolE(value)
+		if err != nil {
+			return unmarshalError(value, v, path)
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := cast.ToInt64E(value)
+		if err != nil || v.OverflowInt(i) {
+			return unmarshalError(value, v, path)
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := cast.ToInt64E(value)
+		if err != nil || i < 0 || v.OverflowUint(uint64(i)) {
+			return unmarshalError(value, v, path)
+		}
+		v.SetUint(uint64(i))
+
+	case reflect.Float32, reflect.Float64:
+		f, err := cast.ToFloat64E(value)
+		if err != nil || v.OverflowFloat(f) {
+			return unmarshalError(value, v, path)
+		}
+		v.SetFloat(f)
+
+	case reflect.Slice:
+		if data, ok := value.([]byte); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), data...))
+			return nil
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			return unmarshalError(value, v, path)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, entry := range values {
+			err := unmarshalValue(entry, slice.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+
+	case reflect.Map:
+		values, ok := value.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return unmarshalError(value, v, path)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(values))
+		for key, entry := range values {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err := unmarshalValue(entry, elem, path+"."+key)
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t, ok := value.(time.Time)
+			if !ok {
+				return unmarshalError(value, v, path)
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return unmarshalError(value, v, path)
+		}
+		for i := 0; i < v.NumField(); i++ {
+			name, _, ok := fieldInfo(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			entry, ok := values[name]
+			if !ok {
+				continue
+			}
+			err := unmarshalValue(entry, v.Field(i), path+"."+name)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		return unmarshalError(value, v, path)
+	}
+	return nil
+}
+
+// unmarshalError for value that can not be stored in target
+func unmarshalError(value interface{}, v reflect.Value, path string) error {
+	if path == "" {
+		return fmt.Errorf("cannot unmarshal %T into %s", value, v.Type())
+	}
+	return fmt.Errorf("cannot unmarshal %T into %s (%s)", value, v.Type(), strings.TrimPrefix(path, "."))
+}
+
+// fieldInfo returns the RPC name and options of the struct field
+func fieldInfo(field reflect.StructField) (string, bool, bool) {
+	// ignore unexported fields
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+
+	tag := field.Tag.Get(tagName)
+	if tag == "-" {
+		return "", false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	var omitEmpty bool
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
+
+// isEmptyValue returns true if value is the zero value of its type
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package rpc
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMode int
+
+type testChild struct {
+	Name  string `xmlrpc:"NAME"`
+	Value *int   `xmlrpc:"VALUE,omitempty"`
+}
+
+type testStruct struct {
+	Address  string               `xmlrpc:"ADDRESS"`
+	Flags    int32                `xmlrpc:"FLAGS"`
+	Version  uint8                `xmlrpc:"VERSION"`
+	Level    float64              `xmlrpc:"LEVEL"`
+	Visible  bool                 `xmlrpc:"VISIBLE"`
+	Mode     testMode             `xmlrpc:"MODE"`
+	Children []string             `xmlrpc:"CHILDREN"`
+	Default  interface{}          `xmlrpc:"DEFAULT"`
+	Data     []byte               `xmlrpc:"DATA,omitempty"`
+	Time     time.Time            `xmlrpc:"TIME,omitempty"`
+	Child    *testChild           `xmlrpc:"CHILD,omitempty"`
+	Entries  []testChild          `xmlrpc:"ENTRIES,omitempty"`
+	Values   map[string]testChild `xmlrpc:"VALUES,omitempty"`
+	Untagged string
+	Ignored  string `xmlrpc:"-"`
+	private  string
+}
+
+func TestUnmarshal(t *testing.T) {
+	ass := assert.New(t)
+
+	value := 42
+	var data testStruct
+	ass.NoError(Unmarshal(map[string]interface{}{
+		"ADDRESS":  "OEQ1234567",
+		"FLAGS":    int32(3),
+		"VERSION":  "5",
+		"LEVEL":    int32(1),
+		"VISIBLE":  "true",
+		"MODE":     int32(2),
+		"CHILDREN": []interface{}{"OEQ1234567:1", "OEQ1234567:2"},
+		"DEFAULT":  int32(7),
+		"DATA":     []byte("test"),
+		"TIME":     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"CHILD": map[string]interface{}{
+			"NAME":  "child",
+			"VALUE": int32(42),
+		},
+		"ENTRIES": []interface{}{
+			map[string]interface{}{"NAME": "entry"},
+		},
+		"VALUES": map[string]interface{}{
+			"aaa": map[string]interface{}{"NAME": "value"},
+		},
+		"Untagged": "untagged",
+		"Ignored":  "ignored",
+		"-":        "ignored",
+		"UNKNOWN":  "unknown",
+	}, &data))
+
+	ass.Equal(testStruct{
+		Address:  "OEQ1234567",
+		Flags:    3,
+		Version:  5,
+		Level:    1,
+		Visible:  true,
+		Mode:     2,
+		Children: []string{"OEQ1234567:1", "OEQ1234567:2"},
+		Default:  int32(7),
+		Data:     []byte("test"),
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Child: &testChild{
+			Name:  "child",
+			Value: &value,
+		},
+		Entries: []testChild{{Name: "entry"}},
+		Values: map[string]testChild{
+			"aaa": {Name: "value"},
+		},
+		Untagged: "untagged",
+	}, data)
+
+	// nil values reset the target
+	ass.NoError(Unmarshal(map[string]interface{}{
+		"CHILD": nil,
+	}, &data))
+	ass.Nil(data.Child)
+
+	var i interface{}
+	ass.NoError(Unmarshal("test", &i))
+	ass.Equal("test", i)
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.EqualError(Unmarshal("test", nil),
+		"target must be a non-nil pointer")
+	ass.EqualError(Unmarshal("test", testStruct{}),
+		"target must be a non-nil pointer")
+
+	var data testStruct
+	ass.EqualError(Unmarshal("test", &data),
+		"cannot unmarshal string into rpc.testStruct")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"FLAGS": "abc",
+	}, &data), "cannot unmarshal string into int32 (FLAGS)")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"VERSION": int32(512),
+	}, &data), "cannot unmarshal int32 into uint8 (VERSION)")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"ADDRESS": []interface{}{},
+	}, &data), "cannot unmarshal []interface {} into string (ADDRESS)")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"CHILDREN": []interface{}{[]interface{}{}},
+	}, &data), "cannot unmarshal []interface {} into string (CHILDREN[0])")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"VALUES": map[string]interface{}{
+			"aaa": map[string]interface{}{"NAME": map[string]interface{}{}},
+		},
+	}, &data), "cannot unmarshal map[string]interface {} into string (VALUES.aaa.NAME)")
+	ass.EqualError(Unmarshal(map[string]interface{}{
+		"TIME": "now",
+	}, &data), "cannot unmarshal string into time.Time (TIME)")
+
+	var err error
+	ass.EqualError(Unmarshal("test", &err),
+		"cannot unmarshal string into error")
+}
+
+func TestMarshal(t *testing.T) {
+	ass := assert.New(t)
+
+	data, err := Marshal(testStruct{
+		Address:  "OEQ1234567",
+		Flags:    3,
+		Version:  5,
+		Mode:     2,
+		Children: []string{"OEQ1234567:1"},
+		Child: &testChild{
+			Name: "child",
+		},
+		Values: map[string]testChild{
+			"aaa": {Name: "value"},
+		},
+		Untagged: "untagged",
+		Ignored:  "ignored",
+		private:  "private",
+	})
+	ass.NoError(err)
+	ass.Equal(map[string]interface{}{
+		"ADDRESS":  "OEQ1234567",
+		"FLAGS":    int64(3),
+		"VERSION":  int64(5),
+		"LEVEL":    float64(0),
+		"VISIBLE":  false,
+		"MODE":     int64(2),
+		"CHILDREN": []interface{}{"OEQ1234567:1"},
+		"DEFAULT":  nil,
+		"CHILD": map[string]interface{}{
+			"NAME": "child",
+		},
+		"VALUES": map[string]interface{}{
+			"aaa": map[string]interface{}{
+				"NAME": "value",
+			},
+		},
+		"Untagged": "untagged",
+	}, data)
+
+	// result can be encoded
+	_, err = Request{Method: "test", Params: []interface{}{data}}.MarshalBinary()
+	ass.NoError(err)
+	_, err = xml.Marshal(Request{Method: "test", Params: []interface{}{data}})
+	ass.NoError(err)
+
+	value, err := Marshal([2]byte{1, 2})
+	ass.NoError(err)
+	ass.Equal([]byte{1, 2}, value)
+
+	value, err = Marshal(nil)
+	ass.NoError(err)
+	ass.Nil(value)
+
+	_, err = Marshal(map[int]string{})
+	ass.EqualError(err, "unsupported map key type int")
+
+	_, err = Marshal(make(chan int))
+	ass.EqualError(err, "unsupported type chan int")
+
+	_, err = Marshal(uint64(1 << 63))
+	ass.Equal(errors.New("integer value out of range"), err)
+}
@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// ClientOption configures an RPC client
+type ClientOption func(*clientOptions)
+
+// clientOptions shared by XML and binary RPC clients
+type clientOptions struct {
+	username  string
+	password  string
+	tlsConfig *tls.Config
+	client    *http.Client
+	timeout   time.Duration
+}
+
+// WithBasicAuth sets the credentials used for each request
+func WithBasicAuth(username, password string) ClientOption {
+	return func(o *clientOptions) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for HTTPS URLs and
+// binary RPC connections (e.g. custom CA or InsecureSkipVerify)
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for XML RPC requests
+// -> the TLS configuration is not applied to a custom client
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.client = client
+	}
+}
+
+// WithTimeout sets the timeout of calls without a context deadline
+// (default 5 seconds)
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// newClientOptions with the given options applied
+func newClientOptions(options []ClientOption) *clientOptions {
+	o := &clientOptions{
+		timeout: time.Second * 5,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// httpClient for the options
+func (o *clientOptions) httpClient() *http.Client {
+	if o.client != nil {
+		return o.client
+	}
+	if o.tlsConfig == nil {
+		return new(http.Client)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: o.tlsConfig,
+		},
+	}
+}
+
+// hasAuth returns true if credentials are set
+func (o *clientOptions) hasAuth() bool {
+	return o.username != "" || o.password != ""
+}
+
+// ServerOption configures an RPC server
+type ServerOption func(*serverOptions)
+
+// serverOptions of XML and binary RPC servers
+type serverOptions struct {
+	address string
+}
+
+// WithListenAddress sets the address the server listens on
+// (default "0.0.0.0:0" -> all IPv4 interfaces on a random port)
+// -> "[::]:0" listens on IPv6 and ":0" on IPv4 and IPv6
+func WithListenAddress(address string) ServerOption {
+	return func(o *serverOptions) {
+		o.address = address
+	}
+}
+
+// newServerOptions with the given options applied
+func newServerOptions(options []ServerOption) *serverOptions {
+	o := &serverOptions{
+		address: "0.0.0.0:0",
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOptions(t *testing.T) {
+	ass := assert.New(t)
+
+	o := newClientOptions(nil)
+	ass.Equal(time.Second*5, o.timeout)
+	ass.False(o.hasAuth())
+	ass.Equal(new(http.Client), o.httpClient())
+
+	config := new(tls.Config)
+	o = newClientOptions([]ClientOption{
+		WithBasicAuth("user", ""),
+		WithTLSConfig(config),
+		WithTimeout(time.Second),
+	})
+	ass.Equal(time.Second, o.timeout)
+	ass.True(o.hasAuth())
+	ass.Equal(config, o.httpClient().Transport.(*http.Transport).TLSClientConfig)
+
+	client := new(http.Client)
+	o = newClientOptions([]ClientOption{
+		WithTLSConfig(config),
+		WithHTTPClient(client),
+	})
+	ass.True(client == o.httpClient())
+}
+
+func TestServerOptions(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal("0.0.0.0:0", newServerOptions(nil).address)
+
+	server, err := NewServer(nil, WithListenAddress("127.0.0.1:0"))
+	ass.NoError(err)
+	ass.Equal(net.IPv4(127, 0, 0, 1).String(),
+		server.listener.Addr().(*net.TCPAddr).IP.String())
+	ass.NoError(server.listener.Close())
+}
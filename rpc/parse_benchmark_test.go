@@ -0,0 +1,272 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html/charset"
+)
+
+// etreeParseResponse is the previous DOM based parser used as reference
+func etreeParseResponse(reader io.Reader) (*Response, error) {
+	doc := etree.NewDocument()
+	doc.ReadSettings.CharsetReader = charset.NewReaderLabel
+	_, err := doc.ReadFrom(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := doc.FindElements("/methodResponse/params/param/value")
+	response := &Response{
+		Params: make([]interface{}, len(elements)),
+	}
+	for idx, element := range elements {
+		response.Params[idx], err = etreeParseValue(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// etreeParseRequest is the previous DOM based parser used as reference
+func etreeParseRequest(reader io.Reader) (*Request, error) {
+	doc := etree.NewDocument()
+	doc.ReadSettings.CharsetReader = charset.NewReaderLabel
+	_, err := doc.ReadFrom(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := doc.FindElements("/methodCall/params/param/value")
+	request := &Request{
+		Params: make([]interface{}, len(elements)),
+	}
+	for idx, element := range elements {
+		request.Params[idx], err = etreeParseValue(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nameElement := doc.FindElement("/methodCall/methodName")
+	if nameElement == nil {
+		return nil, errors.New("method name is missing")
+	}
+	request.Method = strings.TrimSpace(nameElement.Text())
+	return request, nil
+}
+
+// etreeParseValue is the previous DOM based value parser used as reference
+func etreeParseValue(element *etree.Element) (interface{}, error) {
+	children := element.ChildElements()
+	if len(children) == 0 {
+		return strings.TrimSpace(element.Text()), nil
+	}
+	e := children[0]
+
+	switch e.Tag {
+	case "string":
+		return strings.TrimSpace(e.Text()), nil
+	case "int", "i4":
+		return cast.ToInt32E(strings.TrimSpace(e.Text()))
+	case "boolean":
+		return cast.ToBoolE(strings.TrimSpace(e.Text()))
+	case "double":
+		return cast.ToFloat64E(strings.TrimSpace(e.Text()))
+
+	case "array":
+		elements := e.FindElements("./data/value")
+		values := make([]interface{}, len(elements))
+		var err error
+		for idx, valueElement := range elements {
+			values[idx], err = etreeParseValue(valueElement)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	case "struct":
+		elements := e.FindElements("./member")
+		values := make(map[string]interface{}, len(elements))
+		var err error
+		for _, memberElement := range elements {
+			nameElement := memberElement.SelectElement("name")
+			if nameElement == nil {
+				return nil, errors.New("missing struct name element")
+			}
+			valueElement := memberElement.SelectElement("value")
+			if valueElement == nil {
+				return nil, errors.New("missing struct value element")
+			}
+			values[nameElement.Text()], err = etreeParseValue(valueElement)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+
+	default:
+		return nil, fmt.Errorf("invalid value type %s", e.Tag)
+	}
+}
+
+// listDevicesPayload creates a listDevices response of a CCU with the
+// given number of devices with 4 channels each
+func listDevicesPayload(count int) []byte {
+	devices := make([]interface{}, 0, count*5)
+	for i := 0; i < count; i++ {
+		address := fmt.Sprintf("OEQ%07d", i)
+		children := make([]interface{}, 4)
+		for c := range children {
+			children[c] = fmt.Sprintf("%s:%d", address, c)
+		}
+
+		devices = append(devices, map[string]interface{}{
+			"ADDRESS":    address,
+			"CHILDREN":   children,
+			"FIRMWARE":   "2.8",
+			"FLAGS":      1,
+			"INTERFACE":  "NEQ0123456",
+			"PARAMSETS":  []interface{}{"MASTER"},
+			"PARENT":     "",
+			"RF_ADDRESS": 1234567 + i,
+			"ROAMING":    0,
+			"RX_MODE":    1,
+			"TYPE":       "HM-LC-Sw1-FM",
+			"UPDATABLE":  1,
+			"VERSION":    12,
+		})
+		for c := range children {
+			devices = append(devices, map[string]interface{}{
+				"ADDRESS":           children[c],
+				"AES_ACTIVE":        0,
+				"DIRECTION":         2,
+				"FLAGS":             1,
+				"INDEX":             c,
+				"LINK_SOURCE_ROLES": "",
+				"LINK_TARGET_ROLES": "SWITCH",
+				"PARAMSETS":         []interface{}{"LINK", "MASTER", "VALUES"},
+				"PARENT":            address,
+				"PARENT_TYPE":       "HM-LC-Sw1-FM",
+				"TYPE":              "SWITCH",
+				"VERSION":           12,
+			})
+		}
+	}
+
+	data, err := xml.Marshal(&Response{Params: []interface{}{devices}})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// multicallPayload creates a system.multicall request with the given
+// number of events
+func multicallPayload(count int) []byte {
+	calls := make([]interface{}, count)
+	for i := range calls {
+		calls[i] = map[string]interface{}{
+			"methodName": "event",
+			"params": []interface{}{
+				"go-rf",
+				fmt.Sprintf("OEQ%07d:1", i),
+				"LEVEL",
+				float64(i) / float64(count),
+			},
+		}
+	}
+
+	data, err := xml.Marshal(Request{
+		Method: "system.multicall",
+		Params: []interface{}{calls},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestParse_etreeEquivalence(t *testing.T) {
+	ass := assert.New(t)
+
+	payload := listDevicesPayload(10)
+	expectedResponse, err := etreeParseResponse(bytes.NewReader(payload))
+	ass.NoError(err)
+	response, err := ParseResponse(bytes.NewReader(payload))
+	ass.NoError(err)
+	ass.Equal(expectedResponse, response)
+
+	payload = multicallPayload(10)
+	expectedRequest, err := etreeParseRequest(bytes.NewReader(payload))
+	ass.NoError(err)
+	request, err := ParseRequest(bytes.NewReader(payload))
+	ass.NoError(err)
+	ass.Equal(expectedRequest, request)
+}
+
+func BenchmarkParseResponse_listDevices(b *testing.B) {
+	payload := listDevicesPayload(300)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := ParseResponse(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseResponse_listDevices_etree(b *testing.B) {
+	payload := listDevicesPayload(300)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := etreeParseResponse(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseRequest_multicall(b *testing.B) {
+	payload := multicallPayload(200)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := ParseRequest(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseRequest_multicall_etree(b *testing.B) {
+	payload := multicallPayload(200)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := etreeParseRequest(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
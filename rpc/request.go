@@ -1,13 +1,13 @@
 package rpc
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"io"
+	"math"
 	"strings"
-
-	"github.com/beevik/etree"
-	"golang.org/x/net/html/charset"
+	"time"
 )
 
 // Request for XML RPCs
@@ -65,33 +65,39 @@ func (r Request) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // ParseRequest from XML
 func ParseRequest(reader io.Reader) (*Request, error) {
-	doc := etree.NewDocument()
-	doc.ReadSettings.CharsetReader = charset.NewReaderLabel
-	_, err := doc.ReadFrom(reader)
-	if err != nil {
-		return nil, err
-	}
-
-	// handle parameters
-	elements := doc.FindElements("/methodCall/params/param/value")
 	request := &Request{
-		Params: make([]interface{}, len(elements)),
+		Params: []interface{}{},
 	}
+	var hasName bool
+	err := parseDocument(reader, func(d *xml.Decoder, path string) (bool, error) {
+		switch path {
+		case "methodCall/params/param/value":
+			value, err := parseValue(d)
+			if err != nil {
+				return true, err
+			}
+			request.Params = append(request.Params, value)
+			return true, nil
 
-	for idx, element := range elements {
-		request.Params[idx], err = parseValue(element)
-		if err != nil {
-			return nil, err
+		case "methodCall/methodName":
+			name, err := readText(d)
+			if err != nil {
+				return true, err
+			}
+			request.Method = strings.TrimSpace(name)
+			hasName = true
+			return true, nil
 		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// handle name
-	nameElement := doc.FindElement("/methodCall/methodName")
-	if nameElement == nil {
+	if !hasName {
 		return nil, errors.New("method name is missing")
 	}
-	request.Method = strings.TrimSpace(nameElement.Text())
-
 	return request, nil
 }
 
@@ -132,12 +138,46 @@ func encodeValue(value interface{}, e *xml.Encoder) error {
 			return err
 		}
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		err = e.EncodeElement(v, xml.StartElement{
-			Name: xml.Name{Local: "int"},
+		i, err := integerValue(v)
+		if err != nil {
+			return err
+		}
+
+		// use i8 extension only if value exceeds 32 bit
+		tag := "int"
+		if i > math.MaxInt32 || i < math.MinInt32 {
+			tag = "i8"
+		}
+		err = e.EncodeElement(i, xml.StartElement{
+			Name: xml.Name{Local: tag},
 		})
 		if err != nil {
 			return err
 		}
+	case []byte:
+		err = e.EncodeElement(base64.StdEncoding.EncodeToString(v), xml.StartElement{
+			Name: xml.Name{Local: "base64"},
+		})
+		if err != nil {
+			return err
+		}
+	case time.Time:
+		err = e.EncodeElement(v.Format(dateTimeFormat), xml.StartElement{
+			Name: xml.Name{Local: "dateTime.iso8601"},
+		})
+		if err != nil {
+			return err
+		}
+	case nil:
+		nilName := xml.Name{Local: "nil"}
+		err = e.EncodeToken(xml.StartElement{Name: nilName})
+		if err != nil {
+			return err
+		}
+		err = e.EncodeToken(xml.EndElement{Name: nilName})
+		if err != nil {
+			return err
+		}
 	case []interface{}:
 		arrayName := xml.Name{Local: "array"}
 		err = e.EncodeToken(xml.StartElement{Name: arrayName})
@@ -208,3 +248,37 @@ func encodeValue(value interface{}, e *xml.Encoder) error {
 
 	return e.EncodeToken(xml.EndElement{Name: valueName})
 }
+
+// integerValue converts any integer type to int64
+func integerValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, errors.New("integer value out of range")
+		}
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, errors.New("integer value out of range")
+		}
+		return int64(v), nil
+	default:
+		return 0, errors.New("unknown value type")
+	}
+}
@@ -1,9 +1,11 @@
 package rpc
 
 import (
+	"bytes"
 	"encoding/xml"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -209,3 +211,37 @@ func TestRequest_encodeValue(t *testing.T) {
 	_, err = xml.MarshalIndent(request, "", "  ")
 	ass.EqualError(err, "unknown value type")
 }
+
+func TestRequest_encodeValue_extended(t *testing.T) {
+	ass := assert.New(t)
+
+	request := Request{
+		Method: "test",
+		Params: []interface{}{
+			int64(1099511627776),
+			[]byte("test"),
+			time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			nil,
+		},
+	}
+
+	data, err := xml.Marshal(request)
+	ass.NoError(err)
+	ass.Equal("<methodCall><methodName>test</methodName><params>"+
+		"<param><value><i8>1099511627776</i8></value></param>"+
+		"<param><value><base64>dGVzdA==</base64></value></param>"+
+		"<param><value><dateTime.iso8601>20200102T03:04:05</dateTime.iso8601></value></param>"+
+		"<param><value><nil></nil></value></param>"+
+		"</params></methodCall>", string(data))
+
+	// parsed request contains same values
+	parsed, err := ParseRequest(bytes.NewReader(data))
+	ass.NoError(err)
+	ass.Equal(&request, parsed)
+
+	_, err = xml.Marshal(Request{
+		Method: "test",
+		Params: []interface{}{uint64(1 << 63)},
+	})
+	ass.EqualError(err, "integer value out of range")
+}
@@ -1,13 +1,14 @@
 package rpc
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
-	"github.com/beevik/etree"
 	"github.com/spf13/cast"
 	"golang.org/x/net/html/charset"
 )
@@ -18,6 +19,11 @@ type Fault struct {
 	String string
 }
 
+// Error returns the fault as error message
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%s (%d)", f.String, f.Code)
+}
+
 // toMap returns data for fault entry
 func (f *Fault) toMap() map[string]interface{} {
 	return map[string]interface{}{
@@ -104,102 +110,358 @@ func (r *Response) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // ParseResponse from XML
 func ParseResponse(reader io.Reader) (*Response, error) {
-	doc := etree.NewDocument()
-	doc.ReadSettings.CharsetReader = charset.NewReaderLabel
-	_, err := doc.ReadFrom(reader)
+	response := new(Response)
+	err := parseDocument(reader, func(d *xml.Decoder, path string) (bool, error) {
+		switch path {
+		case "methodResponse/params/param/value":
+			value, err := parseValue(d)
+			if err != nil {
+				return true, err
+			}
+			response.Params = append(response.Params, value)
+			return true, nil
+
+		case "methodResponse/fault/value":
+			value, err := parseValue(d)
+			if err != nil {
+				return true, err
+			}
+			data, ok := value.(map[string]interface{})
+			if !ok {
+				return true, errors.New("invalid fault value")
+			}
+			response.Fault = &Fault{
+				Code:   cast.ToInt32(data["faultCode"]),
+				String: cast.ToString(data["faultString"]),
+			}
+			return true, nil
+		}
+		return false, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// handle parameters
-	elements := doc.FindElements("/methodResponse/params/param/value")
-	response := &Response{
-		Params: make([]interface{}, len(elements)),
+	if response.Params == nil {
+		response.Params = []interface{}{}
 	}
+	return response, nil
+}
 
-	for idx, element := range elements {
-		response.Params[idx], err = parseValue(element)
+// parseDocument reads the XML document and calls handle for each element
+// with the path of the element (e.g. "methodResponse/params")
+// -> if handle returns true the element was consumed by handle
+func parseDocument(reader io.Reader, handle func(d *xml.Decoder, path string) (bool, error)) error {
+	d := xml.NewDecoder(reader)
+	d.CharsetReader = charset.NewReaderLabel
+
+	var path []string
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			handled, err := handle(d, strings.Join(path, "/"))
+			if err != nil {
+				return err
+			}
+			if handled {
+				path = path[:len(path)-1]
+			}
+
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
 		}
 	}
+}
 
-	// handle faults
-	faultElement := doc.FindElement("/methodResponse/fault/value")
-	if faultElement != nil {
-		value, err := parseValue(faultElement)
+// nextToken returns the next raw token and handles EOF as unexpected
+func nextToken(d *xml.Decoder) (xml.Token, error) {
+	token, err := d.RawToken()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return token, err
+}
+
+// skipElement consumes all tokens until the end of the current element
+func skipElement(d *xml.Decoder) error {
+	for depth := 0; ; {
+		token, err := nextToken(d)
 		if err != nil {
-			return nil, err
-		}
-		data, ok := value.(map[string]interface{})
-		if !ok {
-			return nil, errors.New("invalid fault value")
+			return err
 		}
-		response.Fault = &Fault{
-			Code:   cast.ToInt32(data["faultCode"]),
-			String: cast.ToString(data["faultString"]),
+
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
 		}
 	}
+}
 
-	return response, nil
+// readText of the current element and consumes it
+// (text of child elements is ignored)
+func readText(d *xml.Decoder) (string, error) {
+	var text []byte
+	for {
+		token, err := nextToken(d)
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.StartElement:
+			err = skipElement(d)
+			if err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			return string(text), nil
+		}
+	}
 }
 
 // parseValue element to go interface
-func parseValue(element *etree.Element) (interface{}, error) {
+// -> start element of value must already be consumed
+func parseValue(d *xml.Decoder) (interface{}, error) {
 	// use text of element if child elements are missing
-	children := element.ChildElements()
-	if len(children) == 0 {
-		return strings.TrimSpace(element.Text()), nil
+	var text []byte
+	var start xml.StartElement
+	for start.Name.Local == "" {
+		token, err := nextToken(d)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.StartElement:
+			start = t
+		case xml.EndElement:
+			return strings.TrimSpace(string(text)), nil
+		}
+	}
+
+	value, err := parseTypedValue(d, start)
+	if err != nil {
+		return nil, err
+	}
+
+	// ignore everything else in value element
+	return value, skipElement(d)
+}
+
+// parseTypedValue converts the type element to go interface
+// -> start element of type must already be consumed
+func parseTypedValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "array":
+		return parseArray(d)
+	case "struct":
+		return parseStruct(d)
+	}
+
+	text, err := readText(d)
+	if err != nil {
+		return nil, err
 	}
-	e := children[0]
 
-	switch e.Tag {
+	switch start.Name.Local {
 	case "string":
-		return strings.TrimSpace(e.Text()), nil
+		return strings.TrimSpace(text), nil
 
 	case "int", "i4":
-		return cast.ToInt32E(strings.TrimSpace(e.Text()))
+		return cast.ToInt32E(strings.TrimSpace(text))
+
+	case "i8":
+		return cast.ToInt64E(strings.TrimSpace(text))
 
 	case "boolean":
-		return cast.ToBoolE(strings.TrimSpace(e.Text()))
+		return cast.ToBoolE(strings.TrimSpace(text))
 
 	case "double":
-		return cast.ToFloat64E(strings.TrimSpace(e.Text()))
+		return cast.ToFloat64E(strings.TrimSpace(text))
 
-	case "array":
-		elements := e.FindElements("./data/value")
-		values := make([]interface{}, len(elements))
-		var err error
-		for idx, valueElement := range elements {
-			values[idx], err = parseValue(valueElement)
+	case "base64":
+		return base64.StdEncoding.DecodeString(
+			strings.Join(strings.Fields(text), ""))
+
+	case "dateTime.iso8601":
+		return parseDateTime(strings.TrimSpace(text))
+
+	case "nil":
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("invalid value type %s", start.Name.Local)
+	}
+}
+
+// parseArray values of array element
+func parseArray(d *xml.Decoder) (interface{}, error) {
+	values := make([]interface{}, 0)
+	for {
+		token, err := nextToken(d)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			return values, nil
+
+		case xml.StartElement:
+			if t.Name.Local != "data" {
+				err = skipElement(d)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			values, err = parseArrayData(d, values)
 			if err != nil {
 				return nil, err
 			}
 		}
-		return values, nil
+	}
+}
 
-	case "struct":
-		elements := e.FindElements("./member")
-		values := make(map[string]interface{}, len(elements))
-		var err error
-		for _, memberElement := range elements {
-			nameElement := memberElement.SelectElement("name")
-			if nameElement == nil {
-				return nil, errors.New("missing struct name element")
+// parseArrayData values of data element in array
+func parseArrayData(d *xml.Decoder, values []interface{}) ([]interface{}, error) {
+	for {
+		token, err := nextToken(d)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			return values, nil
+
+		case xml.StartElement:
+			if t.Name.Local != "value" {
+				err = skipElement(d)
+				if err != nil {
+					return nil, err
+				}
+				continue
 			}
-			valueElement := memberElement.SelectElement("value")
-			if valueElement == nil {
-				return nil, errors.New("missing struct value element")
+
+			value, err := parseValue(d)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
+}
+
+// parseStruct members of struct element
+func parseStruct(d *xml.Decoder) (interface{}, error) {
+	values := make(map[string]interface{})
+	for {
+		token, err := nextToken(d)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			return values, nil
+
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				err = skipElement(d)
+				if err != nil {
+					return nil, err
+				}
+				continue
 			}
 
-			values[nameElement.Text()], err = parseValue(valueElement)
+			err = parseMember(d, values)
 			if err != nil {
 				return nil, err
 			}
 		}
-		return values, nil
+	}
+}
 
-	default:
-		return nil, fmt.Errorf("invalid value type %s", e.Tag)
+// parseMember of struct and store it in values
+func parseMember(d *xml.Decoder, values map[string]interface{}) error {
+	var name string
+	var value interface{}
+	var hasName, hasValue bool
+	for {
+		token, err := nextToken(d)
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			if !hasName {
+				return errors.New("missing struct name element")
+			}
+			if !hasValue {
+				return errors.New("missing struct value element")
+			}
+			values[name] = value
+			return nil
+
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "name" && !hasName:
+				name, err = readText(d)
+				hasName = true
+			case t.Name.Local == "value" && !hasValue:
+				value, err = parseValue(d)
+				hasValue = true
+			default:
+				err = skipElement(d)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// format of dateTime.iso8601 values
+const dateTimeFormat = "20060102T15:04:05"
+
+// accepted formats of dateTime.iso8601 values
+var dateTimeFormats = []string{
+	dateTimeFormat,
+	"20060102T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// parseDateTime value in one of the known formats
+func parseDateTime(value string) (time.Time, error) {
+	for _, format := range dateTimeFormats {
+		t, err := time.Parse(format, value)
+		if err == nil {
+			return t, nil
+		}
 	}
+	return time.Time{}, fmt.Errorf("invalid dateTime value %s", value)
 }
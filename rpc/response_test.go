@@ -1,13 +1,15 @@
 package rpc
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/beevik/etree"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,6 +27,16 @@ func TestFault_toMap(t *testing.T) {
 	}, fault.toMap())
 }
 
+func TestFault_Error(t *testing.T) {
+	ass := assert.New(t)
+
+	var err error = &Fault{
+		Code:   -2,
+		String: "UNKNOWN_DEVICE",
+	}
+	ass.EqualError(err, "UNKNOWN_DEVICE (-2)")
+}
+
 func TestResponse_FirstParam(t *testing.T) {
 	ass := assert.New(t)
 
@@ -77,6 +89,20 @@ func TestParseResponse(t *testing.T) {
 	ass.Equal("faultString", resp.Fault.String)
 }
 
+func TestParseResponse_charset(t *testing.T) {
+	ass := assert.New(t)
+
+	resp, err := ParseResponse(strings.NewReader(
+		"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>" +
+			"<methodResponse><params><param><value>K\xfcche</value></param></params></methodResponse>"))
+	ass.NoError(err)
+	ass.Equal([]interface{}{"K\u00fcche"}, resp.Params)
+
+	_, err = ParseResponse(strings.NewReader(
+		"<methodResponse><params><param><value><struct><member><name>aaa</name>"))
+	ass.Equal(io.ErrUnexpectedEOF, err)
+}
+
 var valueTestData = []struct {
 	name  string
 	xml   string
@@ -107,6 +133,46 @@ var valueTestData = []struct {
 	"<i4>42</i4>",
 	int32(42),
 	nil,
+}, {
+	"i8",
+	"<i8>1099511627776</i8>",
+	int64(1099511627776),
+	nil,
+}, {
+	"ex_i8",
+	`<ex:i8 xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions">-42</ex:i8>`,
+	int64(-42),
+	nil,
+}, {
+	"base64",
+	"<base64>dGVz\n  dA==</base64>",
+	[]byte("test"),
+	nil,
+}, {
+	"base64_invalid",
+	"<base64>###</base64>",
+	nil,
+	base64.CorruptInputError(0),
+}, {
+	"dateTime",
+	"<dateTime.iso8601>20200102T03:04:05</dateTime.iso8601>",
+	time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	nil,
+}, {
+	"dateTime_extended",
+	"<dateTime.iso8601>2020-01-02T03:04:05</dateTime.iso8601>",
+	time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	nil,
+}, {
+	"dateTime_invalid",
+	"<dateTime.iso8601>2020</dateTime.iso8601>",
+	nil,
+	errors.New("invalid dateTime value 2020"),
+}, {
+	"nil",
+	"<nil/>",
+	nil,
+	nil,
 }, {
 	"bool_true",
 	"<boolean>1</boolean>",
@@ -160,6 +226,18 @@ var valueTestData = []struct {
 		"bbb": int32(222),
 	},
 	nil,
+}, {
+	"struct_unordered",
+	`<struct>
+  <member>
+    <value><i4>111</i4></value>
+    <name>aaa</name>
+  </member>
+</struct>`,
+	map[string]interface{}{
+		"aaa": int32(111),
+	},
+	nil,
 }, {
 	"struct",
 	`<struct>
@@ -194,11 +272,12 @@ func TestParseValue(t *testing.T) {
 		t.Run(d.name, func(st *testing.T) {
 			ass := assert.New(st)
 
-			doc := etree.NewDocument()
-			ass.NoError(doc.ReadFromString(
+			decoder := xml.NewDecoder(strings.NewReader(
 				fmt.Sprintf("<value>%s</value>", d.xml)))
+			_, err := decoder.RawToken()
+			ass.NoError(err)
 
-			value, err := parseValue(doc.SelectElement("value"))
+			value, err := parseValue(decoder)
 			ass.Equal(d.err, err)
 			ass.Equal(d.value, value)
 		})
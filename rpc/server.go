@@ -21,18 +21,23 @@ type Server struct {
 	running bool
 	mutex   sync.RWMutex
 
+	// binary RPC mode
+	binary      bool
+	connections map[net.Conn]bool
+
 	handler Handler
 }
 
 // NewServer creates new server
-func NewServer(handler Handler) (*Server, error) {
+func NewServer(handler Handler, options ...ServerOption) (*Server, error) {
+	o := newServerOptions(options)
 	s := &Server{
 		handler: handler,
 	}
 	var err error
 
-	// listen on a random port
-	s.listener, err = net.Listen("tcp4", "0.0.0.0:0")
+	// listen on a random port if not defined
+	s.listener, err = net.Listen(listenNetwork(o.address), o.address)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +50,41 @@ func NewServer(handler Handler) (*Server, error) {
 	return s, nil
 }
 
+// listenNetwork returns the network of the listen address
+// -> IPv4 or IPv6 only if host is an IP, both if host is empty or a name
+func listenNetwork(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "tcp"
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// NewBinServer creates new server for the binary RPC protocol
+func NewBinServer(handler Handler, options ...ServerOption) (*Server, error) {
+	s, err := NewServer(handler, options...)
+	if err != nil {
+		return nil, err
+	}
+	s.binary = true
+	s.connections = make(map[net.Conn]bool)
+	return s, nil
+}
+
+// IsBinary returns true if server handles the binary RPC protocol
+func (s *Server) IsBinary() bool {
+	return s.binary
+}
+
 // IsRunning returns true if server is running
 func (s *Server) IsRunning() bool {
 	s.mutex.RLock()
@@ -62,8 +102,13 @@ func (s *Server) Start() {
 		return
 	}
 
+	serve := s.srv.Serve
+	if s.binary {
+		serve = s.serveBinary
+	}
+
 	go func() {
-		if err := s.srv.Serve(s.listener); err != nil && err != http.ErrServerClosed {
+		if err := serve(s.listener); err != nil && err != http.ErrServerClosed {
 			s.mutex.Lock()
 			s.err = err
 			s.mutex.Unlock()
@@ -85,6 +130,10 @@ func (s *Server) Stop() error {
 		return nil
 	}
 
+	if s.binary {
+		return s.stopBinary()
+	}
+
 	// wait up to 2 seconds for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
@@ -105,6 +154,85 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	_ = xml.NewEncoder(writer).Encode(s.handle(rpcRequest))
+}
+
+// serveBinary accepts binary RPC connections until listener is closed
+func (s *Server) serveBinary(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mutex.RLock()
+			closed := !s.running
+			s.mutex.RUnlock()
+			if closed {
+				return http.ErrServerClosed
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		if !s.running {
+			s.mutex.Unlock()
+			_ = conn.Close()
+			return http.ErrServerClosed
+		}
+		s.connections[conn] = true
+		s.mutex.Unlock()
+
+		go s.serveBinaryConn(conn)
+	}
+}
+
+// serveBinaryConn handles requests of a single binary RPC connection
+func (s *Server) serveBinaryConn(conn net.Conn) {
+	defer func() {
+		// invalid requests must not crash the process -> close connection
+		_ = recover()
+
+		s.mutex.Lock()
+		delete(s.connections, conn)
+		s.mutex.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		rpcRequest, err := ParseBinaryRequest(conn)
+		if err != nil {
+			return
+		}
+
+		response := s.handle(rpcRequest)
+		// results of multicall are returned as single array
+		if rpcRequest.Method == "system.multicall" && response.Fault == nil {
+			response = &Response{
+				Params: []interface{}{response.Params},
+			}
+		}
+
+		data, err := response.MarshalBinary()
+		if err != nil {
+			return
+		}
+		_, err = conn.Write(data)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stopBinary closes listener and all open connections
+func (s *Server) stopBinary() error {
+	s.running = false
+	err := s.listener.Close()
+	for conn := range s.connections {
+		_ = conn.Close()
+	}
+	return err
+}
+
+// handle received request
+func (s *Server) handle(rpcRequest *Request) *Response {
 	response := new(Response)
 	switch rpcRequest.Method {
 	case "system.listMethods":
@@ -125,7 +253,14 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		}
 
 		// first parameter contains list of calls
-		functionCalls := rpcRequest.Params[0].([]interface{})
+		functionCalls, ok := rpcRequest.Params[0].([]interface{})
+		if !ok {
+			response.Fault = &Fault{
+				Code:   1,
+				String: "invalid parameter",
+			}
+			break
+		}
 		response.Params = make([]interface{}, len(functionCalls))
 
 		for idx, call := range functionCalls {
@@ -175,6 +310,5 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		response.Params, response.Fault = s.handler(
 			rpcRequest.Method, rpcRequest.Params)
 	}
-
-	_ = xml.NewEncoder(writer).Encode(&response)
+	return response
 }
@@ -3,6 +3,7 @@ package rpc
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -163,3 +164,105 @@ func TestServer_ServeHTTP_multicall(t *testing.T) {
 		},
 	}, response)
 }
+
+func TestServer_binary(t *testing.T) {
+	ass := assert.New(t)
+
+	var handler Handler = func(method string, params []interface{}) ([]interface{}, *Fault) {
+		switch method {
+		case "event":
+			ass.Equal([]interface{}{"aaa", "bbb"}, params)
+			return []interface{}{"111"}, nil
+		case "panic":
+			panic("test")
+		default:
+			return nil, &Fault{Code: -1, String: "unknown"}
+		}
+	}
+
+	server, err := NewBinServer(handler)
+	ass.NoError(err)
+	ass.True(server.IsBinary())
+	server.Start()
+	time.Sleep(time.Millisecond * 5)
+	ass.True(server.IsRunning())
+
+	client := NewBinClient(fmt.Sprintf("xmlrpc_bin://127.0.0.1:%d", server.Port()))
+
+	response, err := client.Call("event", []interface{}{"aaa", "bbb"})
+	ass.NoError(err)
+	ass.Equal(&Response{Params: []interface{}{"111"}}, response)
+
+	response, err = client.Call("unknown", nil)
+	ass.NoError(err)
+	ass.Equal(&Response{Fault: &Fault{Code: -1, String: "unknown"}}, response)
+
+	response, err = client.Call("system.multicall", []interface{}{
+		[]interface{}{
+			map[string]interface{}{
+				"methodName": "event",
+				"params":     []interface{}{"aaa", "bbb"},
+			},
+		},
+	})
+	ass.NoError(err)
+	ass.Equal(&Response{Params: []interface{}{
+		[]interface{}{
+			[]interface{}{"111"},
+		},
+	}}, response)
+
+	// invalid multicall returns a fault
+	response, err = client.Call("system.multicall", []interface{}{"aaa"})
+	ass.NoError(err)
+	ass.Equal(&Response{Fault: &Fault{Code: 1, String: "invalid parameter"}}, response)
+
+	// panic only closes the connection
+	_, err = client.Call("panic", nil)
+	ass.Error(err)
+	response, err = client.Call("event", []interface{}{"aaa", "bbb"})
+	ass.NoError(err)
+	ass.Equal(&Response{Params: []interface{}{"111"}}, response)
+
+	ass.NoError(server.Stop())
+	time.Sleep(time.Millisecond * 5)
+	ass.False(server.IsRunning())
+
+	_, err = client.Call("event", []interface{}{"aaa", "bbb"})
+	ass.Error(err)
+}
+
+func TestListenNetwork(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal("tcp4", listenNetwork("0.0.0.0:0"))
+	ass.Equal("tcp4", listenNetwork("192.168.1.10:9000"))
+	ass.Equal("tcp6", listenNetwork("[::]:0"))
+	ass.Equal("tcp6", listenNetwork("[fe80::1]:9000"))
+	ass.Equal("tcp", listenNetwork(":9000"))
+	ass.Equal("tcp", listenNetwork("localhost:9000"))
+	ass.Equal("tcp", listenNetwork("invalid"))
+}
+
+func TestServer_IPv6(t *testing.T) {
+	ass := assert.New(t)
+
+	// skip if IPv6 is not available
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 not available")
+	}
+	ass.NoError(listener.Close())
+
+	var handler Handler = func(_ string, params []interface{}) ([]interface{}, *Fault) {
+		return params, nil
+	}
+	server, err := NewServer(handler, WithListenAddress("[::1]:0"))
+	ass.NoError(err)
+	server.Start()
+	defer server.Stop()
+
+	response, err := NewClient(fmt.Sprintf("http://[::1]:%d/", server.Port())).Call("test", []interface{}{"a"})
+	ass.NoError(err)
+	ass.Equal([]interface{}{"a"}, response.Params)
+}
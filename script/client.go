@@ -1,7 +1,9 @@
 package script
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -12,29 +14,61 @@ import (
 // Client interface for remote script client
 type Client interface {
 	Call(script string) (Result, error)
+	CallContext(ctx context.Context, script string) (Result, error)
 }
 
 // NewClient creates new client
-func NewClient(url string) Client {
-	return &client{url, &http.Client{
-		Timeout: time.Second * 5,
-	}}
+func NewClient(url string, options ...ClientOption) Client {
+	o := newClientOptions(options)
+	return &client{
+		URL:     url,
+		client:  o.httpClient(),
+		options: o,
+		timeout: o.timeout,
+	}
 }
 
 // RPC client
 type client struct {
-	URL    string
-	client *http.Client
+	URL     string
+	client  *http.Client
+	options *clientOptions
+	timeout time.Duration
 }
 
-// Call sends an RPC to server
+// Call sends an RPC to server with the default timeout
 func (c *client) Call(script string) (Result, error) {
-	resp, err := c.client.Post(c.URL+"a.exe", "", strings.NewReader(script))
+	return c.CallContext(context.Background(), script)
+}
+
+// CallContext sends an RPC to server and aborts if the context is done
+// (default timeout is used if context has no deadline)
+func (c *client) CallContext(ctx context.Context, script string) (Result, error) {
+	// use default timeout if context has no deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.URL+"a.exe", strings.NewReader(script))
+	if err != nil {
+		return nil, err
+	}
+	if c.options.hasAuth() {
+		req.SetBasicAuth(c.options.username, c.options.password)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("authentication failed")
+	}
+
 	// no processing instruction in response
 	// -> charset.NewReaderLabel not working
 	// -> manually decode iso-8859-1
@@ -1,10 +1,14 @@
 package script
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,3 +37,53 @@ func TestClient_Call(t *testing.T) {
 		"b": "bbb",
 	}, res)
 }
+
+func TestClient_CallContext(t *testing.T) {
+	ass := assert.New(t)
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// block until test is done
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	c := NewClient(server.URL + "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := c.CallContext(ctx, "testScript")
+	ass.Error(err)
+	ass.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func TestClient_TLS(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		if !ok || username != "user" || password != "pass" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, err := rw.Write([]byte(`<xml><a>aaa</a></xml>`))
+		ass.NoError(err)
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	c := NewClient(server.URL+"/", WithTLSConfig(&tls.Config{RootCAs: pool}))
+	_, err := c.Call("testScript")
+	ass.EqualError(err, "authentication failed")
+
+	c = NewClient(server.URL+"/",
+		WithTLSConfig(&tls.Config{RootCAs: pool}),
+		WithBasicAuth("user", "pass"))
+	res, err := c.Call("testScript")
+	ass.NoError(err)
+	ass.Equal(Result{"a": "aaa"}, res)
+}
@@ -0,0 +1,83 @@
+package script
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// ClientOption configures a script client
+type ClientOption func(*clientOptions)
+
+// clientOptions of script client
+type clientOptions struct {
+	username  string
+	password  string
+	tlsConfig *tls.Config
+	client    *http.Client
+	timeout   time.Duration
+}
+
+// WithBasicAuth sets the credentials used for each request
+func WithBasicAuth(username, password string) ClientOption {
+	return func(o *clientOptions) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for HTTPS URLs
+// (e.g. custom CA or InsecureSkipVerify)
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+// -> the TLS configuration is not applied to a custom client
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.client = client
+	}
+}
+
+// WithTimeout sets the timeout of calls without a context deadline
+// (default 5 seconds)
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// newClientOptions with the given options applied
+func newClientOptions(options []ClientOption) *clientOptions {
+	o := &clientOptions{
+		timeout: time.Second * 5,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// httpClient for the options
+func (o *clientOptions) httpClient() *http.Client {
+	if o.client != nil {
+		return o.client
+	}
+	if o.tlsConfig == nil {
+		return new(http.Client)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: o.tlsConfig,
+		},
+	}
+}
+
+// hasAuth returns true if credentials are set
+func (o *clientOptions) hasAuth() bool {
+	return o.username != "" || o.password != ""
+}
@@ -0,0 +1,36 @@
+package script
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientOptions(t *testing.T) {
+	ass := assert.New(t)
+
+	o := newClientOptions(nil)
+	ass.Equal(time.Second*5, o.timeout)
+	ass.False(o.hasAuth())
+	ass.Equal(new(http.Client), o.httpClient())
+
+	config := new(tls.Config)
+	o = newClientOptions([]ClientOption{
+		WithBasicAuth("", "pass"),
+		WithTLSConfig(config),
+		WithTimeout(time.Second),
+	})
+	ass.Equal(time.Second, o.timeout)
+	ass.True(o.hasAuth())
+	ass.Equal(config, o.httpClient().Transport.(*http.Transport).TLSClientConfig)
+
+	client := new(http.Client)
+	o = newClientOptions([]ClientOption{
+		WithTLSConfig(config),
+		WithHTTPClient(client),
+	})
+	ass.True(client == o.httpClient())
+}
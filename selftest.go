@@ -0,0 +1,150 @@
+package homematic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// InterfaceCheck is the result of the callback check of an interface on start
+type InterfaceCheck struct {
+	// Available is false if the interface process was not reachable
+	// -> unavailable interfaces are not checked and not seen as failed
+	Available bool
+	// Init is the error of the init call (connection to the CCU)
+	Init error
+	// Ping is the error of the ping call (connection to the CCU)
+	Ping error
+	// Callback is set if no PONG event was received
+	// (connection from the CCU to the callback server)
+	Callback error
+	// State of the callback connection after the check
+	State ConnectionState
+}
+
+// Err returns the first error of the check or nil if the check succeeded
+func (c InterfaceCheck) Err() error {
+	switch {
+	case c.Init != nil:
+		return fmt.Errorf("init failed: %v", c.Init)
+	case c.Ping != nil:
+		return fmt.Errorf("ping failed: %v", c.Ping)
+	case c.Callback != nil:
+		return fmt.Errorf("callback not reachable: %v", c.Callback)
+	}
+	return nil
+}
+
+// StartError is returned by Start if the check of an interface failed
+// -> event handling is started anyway and failed interfaces are registered
+// again by the supervisor
+type StartError struct {
+	// Checks of all interfaces by interface id
+	Checks map[string]InterfaceCheck
+}
+
+// Error returns the errors of all failed interfaces
+func (e *StartError) Error() string {
+	ids := make([]string, 0, len(e.Checks))
+	for id := range e.Checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []string
+	for _, id := range ids {
+		err := e.Checks[id].Err()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", id, err))
+		}
+	}
+	return "interface check failed: " + strings.Join(errs, ", ")
+}
+
+// checkInterfaces registers on all available interfaces and checks the
+// callback connection with a ping
+// -> returns nil if all available interfaces are connected
+func (c *CCU) checkInterfaces(ctx context.Context) error {
+	c.clientMutex.RLock()
+	checks := make(map[string]InterfaceCheck, len(c.rpcClients))
+	for id := range c.rpcClients {
+		checks[id] = InterfaceCheck{
+			State: ConnectionUnreachable,
+		}
+	}
+	clients := c.availableClients()
+	c.clientMutex.RUnlock()
+
+	// check interfaces in parallel -> wait for PONG can take some time
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for id, client := range clients {
+		wg.Add(1)
+		go func(id string, client rpc.Client) {
+			defer wg.Done()
+			check := c.checkInterface(ctx, id, client)
+
+			mutex.Lock()
+			checks[id] = check
+			mutex.Unlock()
+		}(id, client)
+	}
+	wg.Wait()
+
+	c.clientMutex.Lock()
+	defer c.clientMutex.Unlock()
+
+	var failed bool
+	for id, check := range checks {
+		if !check.Available {
+			continue
+		}
+
+		status := c.interfaceStatus[id]
+		status.State = check.State
+		status.Error = check.Err()
+		switch check.State {
+		case ConnectionConnected:
+			status.LastPong = time.Now()
+		case ConnectionUnreachable:
+			status.Available = false
+		}
+		c.interfaceStatus[id] = status
+		failed = failed || status.Error != nil
+	}
+
+	if failed {
+		return &StartError{Checks: checks}
+	}
+	return nil
+}
+
+// checkInterface registers on the interface and checks the callback
+// connection with a ping
+func (c *CCU) checkInterface(ctx context.Context, id string, client rpc.Client) InterfaceCheck {
+	check := InterfaceCheck{
+		Available: true,
+		State:     ConnectionBroken,
+	}
+
+	check.Init = c.initInterface(ctx, id, client)
+	c.setLastEvent(id, time.Now())
+	if check.Init != nil {
+		return check
+	}
+
+	state, err := c.ping(ctx, id, client)
+	check.State = state
+	switch state {
+	case ConnectionUnreachable:
+		check.Ping = err
+	case ConnectionBroken:
+		check.Callback = err
+	}
+	return check
+}
@@ -0,0 +1,120 @@
+package homematic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestInterfaceCheck_Err(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.NoError(InterfaceCheck{}.Err())
+	ass.EqualError(InterfaceCheck{Init: errors.New("refused")}.Err(),
+		"init failed: refused")
+	ass.EqualError(InterfaceCheck{Ping: errors.New("timeout")}.Err(),
+		"ping failed: timeout")
+	ass.EqualError(InterfaceCheck{Callback: errors.New("no PONG")}.Err(),
+		"callback not reachable: no PONG")
+}
+
+func TestCCU_Start_check(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithPingTimeout(time.Millisecond*100))
+	ass.NoError(err)
+
+	ccu.rpcClients = map[string]rpc.Client{
+		"connected": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			if method == "ping" {
+				go ccu.handleCallback("event", []interface{}{
+					"connected", "CENTRAL", "PONG", params[0],
+				})
+			}
+			return &rpc.Response{}, nil
+		}),
+		"noping": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			if method == "ping" {
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -1, String: "unknown method"},
+				}, nil
+			}
+			return &rpc.Response{}, nil
+		}),
+		"init": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			if method == "init" && params[1] != "" {
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -1, String: "invalid url"},
+				}, nil
+			}
+			return &rpc.Response{}, nil
+		}),
+		"callback": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			return &rpc.Response{}, nil
+		}),
+		"unreachable": testInterfaceClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			return nil, errors.New("refused")
+		}),
+	}
+
+	err = ccu.Start()
+	ass.EqualError(err, "interface check failed: "+
+		"callback: callback not reachable: no PONG event received, "+
+		"init: init failed: invalid url (-1)")
+	ass.NoError(ccu.Stop())
+
+	startErr, ok := err.(*StartError)
+	ass.True(ok)
+	ass.Len(startErr.Checks, 5)
+	ass.Equal(ConnectionConnected, startErr.Checks["connected"].State)
+	ass.NoError(startErr.Checks["connected"].Err())
+	ass.Equal(ConnectionUnknown, startErr.Checks["noping"].State)
+	ass.NoError(startErr.Checks["noping"].Err())
+	ass.Equal(ConnectionBroken, startErr.Checks["init"].State)
+	ass.EqualError(startErr.Checks["init"].Init, "invalid url (-1)")
+	ass.Equal(ConnectionBroken, startErr.Checks["callback"].State)
+	ass.False(startErr.Checks["unreachable"].Available)
+	ass.Equal(ConnectionUnreachable, startErr.Checks["unreachable"].State)
+
+	interfaces := ccu.Interfaces()
+	ass.Equal(ConnectionConnected, interfaces["connected"].State)
+	ass.EqualError(interfaces["init"].Error, "init failed: invalid url (-1)")
+}
+
+func TestCCU_Start_callbackBeforePong(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithInterfaces(InterfaceRF),
+		WithPingTimeout(time.Second))
+	ass.NoError(err)
+	ccu.SetJSONRPCClient(&testJSONClient{})
+
+	ccu.rpcClients["go-rf"] = testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		if method == "ping" {
+			// CCU delivers callbacks one at a time
+			// -> newDevices after init is handled before the PONG
+			go func() {
+				ccu.handleCallback("newDevices", []interface{}{
+					"go-rf",
+					[]interface{}{
+						map[string]interface{}{"ADDRESS": "address"},
+					},
+				})
+				ccu.handleCallback("event", []interface{}{
+					"go-rf", "CENTRAL", "PONG", params[0],
+				})
+			}()
+		}
+		return &rpc.Response{}, nil
+	})
+
+	ass.NoError(ccu.Start())
+	ass.Equal(ConnectionConnected, ccu.Interfaces()["go-rf"].State)
+	ass.NoError(ccu.Stop())
+}
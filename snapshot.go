@@ -0,0 +1,129 @@
+package homematic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// number of parallel requests of a snapshot or poll if not set with
+// WithSnapshot
+const defaultSnapshotConcurrency = 4
+
+// Snapshot loads the values of all devices into the value cache
+// -> values that differ from the cached value are sent as synthetic events
+// to the event streams and value changed handlers
+func (c *CCU) Snapshot() error {
+	return c.SnapshotContext(context.Background())
+}
+
+// SnapshotContext loads the values of all devices into the value cache
+// and aborts if context is done
+func (c *CCU) SnapshotContext(ctx context.Context) error {
+	return c.snapshot(ctx, "")
+}
+
+// snapshot loads the values of all devices of the interface
+// (all interfaces if id is empty)
+func (c *CCU) snapshot(ctx context.Context, id string) error {
+	err := c.UpdateDevicesContext(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	c.deviceMutex.RLock()
+	devices := make([]*Device, 0, len(c.devices))
+	for _, device := range c.devices {
+		if device.HasValues() && (id == "" || device.Interface == id) {
+			devices = append(devices, device)
+		}
+	}
+	c.deviceMutex.RUnlock()
+
+	return c.loadValues(ctx, devices)
+}
+
+// loadValues loads the values of the devices in parallel
+// -> returns the first error after all devices are loaded
+func (c *CCU) loadValues(ctx context.Context, devices []*Device) error {
+	concurrency := c.options.snapshotConcurrency
+	if concurrency < 1 {
+		concurrency = defaultSnapshotConcurrency
+	}
+
+	// limit parallel requests to not overload the CCU
+	var wg sync.WaitGroup
+	var err error
+	var errMutex sync.Mutex
+	limit := make(chan struct{}, concurrency)
+	for _, device := range devices {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(device *Device) {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			e := c.snapshotDevice(ctx, device)
+			if e != nil {
+				// a failed device should not affect the others
+				errMutex.Lock()
+				if err == nil {
+					err = e
+				}
+				errMutex.Unlock()
+			}
+		}(device)
+	}
+	wg.Wait()
+	return err
+}
+
+// snapshotDevice loads the values of the device and sends events for
+// the changed values
+func (c *CCU) snapshotDevice(ctx context.Context, device *Device) error {
+	requestedAt := time.Now()
+	response, err := device.client.CallContext(ctx,
+		"getParamset",
+		[]interface{}{device.Address, "VALUES"})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+
+	values := cast.ToStringMap(response.FirstParam())
+	changed := device.cacheValues(values, requestedAt)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	c.deviceMutex.RLock()
+	deviceTypes := c.deviceTypes(device)
+	c.deviceMutex.RUnlock()
+
+	receivedAt := time.Now()
+	for _, key := range changed {
+		event := Event{
+			Interface:  device.Interface,
+			Address:    device.Address,
+			Parameter:  key,
+			Value:      values[key],
+			ReceivedAt: receivedAt,
+			Synthetic:  true,
+		}
+		c.publishEvent(event, deviceTypes)
+		c.dispatcher.dispatch(event, func() {
+			device.valueChanged(event.Parameter, event.Value)
+		})
+	}
+	return nil
+}
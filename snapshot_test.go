@@ -0,0 +1,103 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+func TestCCU_Snapshot(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1", WithSnapshot(2))
+	ass.NoError(err)
+	ccu.lastUpdate = time.Now()
+
+	var running, maxRunning int32
+	client := testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+		ass.Equal("getParamset", method)
+
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+
+		switch params[0] {
+		case "failed":
+			return nil, errors.New("failed")
+		case "fault":
+			return &rpc.Response{
+				Fault: &rpc.Fault{Code: -2, String: "unknown device"},
+			}, nil
+		}
+		return &rpc.Response{
+			Params: []interface{}{map[string]interface{}{
+				"STATE": true,
+				"LEVEL": 0.5,
+			}},
+		}, nil
+	})
+
+	values := []string{"VALUES"}
+	for _, address := range []string{"a", "b", "c", "d", "failed"} {
+		ccu.devices[address] = &Device{
+			Address:   address,
+			Interface: "test",
+			ParamSets: values,
+			client:    client,
+		}
+	}
+	ccu.devices["master"] = &Device{
+		Address:   "master",
+		ParamSets: []string{"MASTER"},
+		client:    client,
+	}
+	ccu.devices["a"].cacheValue("STATE", false, time.Now(), ValueSourceEvent)
+	ccu.devices["a"].cacheValue("LEVEL", 0.5, time.Now(), ValueSourceEvent)
+
+	var handlerCalls int32
+	ccu.devices["a"].AddValueChangedHandler(func(key string, value interface{}) {
+		ass.Equal("STATE", key)
+		atomic.AddInt32(&handlerCalls, 1)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := ccu.Events(ctx, EventFilter{})
+
+	ass.EqualError(ccu.Snapshot(), "failed")
+	ass.Equal(int32(2), atomic.LoadInt32(&maxRunning))
+
+	// only changed value of device "a" is sent
+	// -> values not cached before are not sent
+	ass.Len(events, 1)
+	event := <-events
+	ass.True(event.Synthetic)
+	ass.Equal("test", event.Interface)
+	ass.Equal("a", event.Address)
+	ass.Equal("STATE", event.Parameter)
+
+	value, err := ccu.devices["b"].CachedValue("LEVEL")
+	ass.NoError(err)
+	ass.Equal(0.5, value.Value)
+	ass.Equal(ValueSourcePoll, value.Source)
+
+	// unchanged values are not sent again
+	ccu.devices["failed"].Address = "fault"
+	ass.EqualError(ccu.snapshot(context.Background(), "test"), "unknown device (-2)")
+	ass.Len(events, 0)
+
+	ccu.dispatcher.stop()
+	ass.Equal(int32(1), atomic.LoadInt32(&handlerCalls))
+}
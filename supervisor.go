@@ -0,0 +1,462 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+)
+
+// ConnectionState of the callback connection of an interface
+type ConnectionState int
+
+// states of the callback connection
+const (
+	// ConnectionUnknown if not checked yet or ping is not supported
+	ConnectionUnknown ConnectionState = iota
+	// ConnectionConnected if the interface answered a ping with a PONG event
+	ConnectionConnected
+	// ConnectionBroken if the interface is reachable but sends no PONG event
+	ConnectionBroken
+	// ConnectionUnreachable if the interface is not reachable
+	ConnectionUnreachable
+)
+
+// String returns the name of the state
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionConnected:
+		return "connected"
+	case ConnectionBroken:
+		return "broken"
+	case ConnectionUnreachable:
+		return "unreachable"
+	default:
+		return "unknown"
+	}
+}
+
+// delay before the device list is updated after an event of an unknown
+// device -> events of further unknown devices are handled with one update
+const reconcileDelay = time.Millisecond * 500
+
+// supervisor checks the callback connection of all interfaces and updates
+// the device list on events of unknown devices and device callbacks in
+// background
+type supervisor struct {
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+
+	pongs    map[string]chan struct{}
+	failures map[string]int
+	nextInit map[string]time.Time
+	counter  uint64
+
+	reconcile chan struct{}
+	unknown   map[string]time.Time
+
+	updates     []func(ctx context.Context)
+	updateReady chan struct{}
+
+	polls map[string]time.Time
+
+	mutex sync.Mutex
+}
+
+// newSupervisor creates a stopped supervisor
+func newSupervisor() *supervisor {
+	return &supervisor{
+		pongs:     make(map[string]chan struct{}),
+		failures:  make(map[string]int),
+		nextInit:  make(map[string]time.Time),
+		reconcile: make(chan struct{}, 1),
+		unknown:   make(map[string]time.Time),
+		polls:     make(map[string]time.Time),
+
+		updateReady: make(chan struct{}, 1),
+	}
+}
+
+// nextPoll returns the time the device with the address is polled next
+func (s *supervisor) nextPoll(address string) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.polls[address]
+}
+
+// setNextPoll sets the time the device with the address is polled next
+func (s *supervisor) setNextPoll(address string, t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.polls[address] = t
+}
+
+// removePoll removes the next poll time of the device with the address
+func (s *supervisor) removePoll(address string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.polls, address)
+}
+
+// unknownAddress requests an update of the device list
+// -> each address triggers an update only once per interval
+func (s *supervisor) unknownAddress(address string, interval time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if requested, ok := s.unknown[address]; ok && time.Since(requested) < interval {
+		return
+	}
+
+	// remove addresses with elapsed interval
+	for unknown, requested := range s.unknown {
+		if time.Since(requested) >= interval {
+			delete(s.unknown, unknown)
+		}
+	}
+	s.unknown[address] = time.Now()
+
+	select {
+	case s.reconcile <- struct{}{}:
+	default:
+	}
+}
+
+// deviceUpdate queues an update of the device list that is executed in
+// background in the order of the calls
+func (s *supervisor) deviceUpdate(update func(ctx context.Context)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.updates = append(s.updates, update)
+	select {
+	case s.updateReady <- struct{}{}:
+	default:
+	}
+}
+
+// takeUpdates returns and removes all queued device updates
+func (s *supervisor) takeUpdates() []func(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	updates := s.updates
+	s.updates = nil
+	return updates
+}
+
+// waitPong registers a ping with the caller id
+func (s *supervisor) waitPong(callerID string) <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pong := make(chan struct{}, 1)
+	s.pongs[callerID] = pong
+	return pong
+}
+
+// cancelPong removes the ping with the caller id
+func (s *supervisor) cancelPong(callerID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.pongs, callerID)
+}
+
+// pong received for the caller id
+// -> returns true if a ping with the caller id is waiting
+func (s *supervisor) pong(callerID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pong, ok := s.pongs[callerID]
+	if ok {
+		select {
+		case pong <- struct{}{}:
+		default:
+		}
+	}
+	return ok
+}
+
+// allowInit returns true if a re init of the interface is allowed
+// -> delay between two re inits is increased on each failure
+func (s *supervisor) allowInit(id string, minBackoff, maxBackoff time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Before(s.nextInit[id]) {
+		return false
+	}
+	s.nextInit[id] = now.Add(backoff(s.failures[id], minBackoff, maxBackoff))
+	s.failures[id]++
+	return true
+}
+
+// connected resets the backoff of the interface
+func (s *supervisor) connected(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.failures, id)
+	delete(s.nextInit, id)
+}
+
+// backoff returns the delay after the given number of failures
+func backoff(failures int, minBackoff, maxBackoff time.Duration) time.Duration {
+	delay := minBackoff
+	for i := 0; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// startSupervisor starts the background check of all interfaces
+func (c *CCU) startSupervisor() {
+	c.supervisor.mutex.Lock()
+	defer c.supervisor.mutex.Unlock()
+
+	// already running?
+	if c.supervisor.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.supervisor.cancel = cancel
+
+	c.supervisor.done.Add(2)
+	go func() {
+		defer c.supervisor.done.Done()
+
+		ticker := time.NewTicker(c.options.pingInterval)
+		defer ticker.Stop()
+		for {
+			c.superviseInterfaces(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	go func() {
+		defer c.supervisor.done.Done()
+		c.reconcileDevices(ctx)
+	}()
+
+	if c.options.pollingMode != PollingDisabled {
+		c.supervisor.done.Add(1)
+		go func() {
+			defer c.supervisor.done.Done()
+			c.pollValues(ctx)
+		}()
+	}
+
+	// load current values in background
+	if c.options.snapshotConcurrency > 0 {
+		c.supervisor.done.Add(1)
+		go func() {
+			defer c.supervisor.done.Done()
+			// failed devices are updated with the next event or snapshot
+			_ = c.snapshot(ctx, "")
+		}()
+	}
+}
+
+// stopSupervisor stops the background check and waits until it is done
+func (c *CCU) stopSupervisor() {
+	c.supervisor.mutex.Lock()
+	cancel := c.supervisor.cancel
+	c.supervisor.cancel = nil
+	c.supervisor.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		c.supervisor.done.Wait()
+	}
+}
+
+// reconcileDevices updates the device list after events of unknown devices
+// and executes queued device updates until the context is done
+func (c *CCU) reconcileDevices(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.supervisor.updateReady:
+			c.runDeviceUpdates(ctx)
+			continue
+		case <-c.supervisor.reconcile:
+		}
+
+		// wait for further unknown devices
+		timer := time.NewTimer(reconcileDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// failed update is retried with the next unknown device
+		_ = c.UpdateDevicesContext(ctx, true)
+	}
+}
+
+// runDeviceUpdates executes all queued device updates
+func (c *CCU) runDeviceUpdates(ctx context.Context) {
+	for _, update := range c.supervisor.takeUpdates() {
+		update(ctx)
+	}
+}
+
+// setLastEvent stores the time of the last event of the interface
+func (c *CCU) setLastEvent(id string, t time.Time) {
+	c.lastEventMutex.Lock()
+	defer c.lastEventMutex.Unlock()
+
+	c.lastClientEvent[id] = t
+}
+
+// lastEvent returns the time of the last event of the interface
+func (c *CCU) lastEvent(id string) time.Time {
+	c.lastEventMutex.Lock()
+	defer c.lastEventMutex.Unlock()
+
+	return c.lastClientEvent[id]
+}
+
+// superviseInterfaces checks the callback connection of all interfaces
+func (c *CCU) superviseInterfaces(ctx context.Context) {
+	// check if unavailable interfaces are back
+	c.discoverInterfaces(ctx, false)
+	c.clientMutex.RLock()
+	clients := c.availableClients()
+	c.clientMutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for id, client := range clients {
+		wg.Add(1)
+		go func(id string, client rpc.Client) {
+			defer wg.Done()
+			c.superviseInterface(ctx, id, client)
+		}(id, client)
+	}
+	wg.Wait()
+}
+
+// superviseInterface checks the callback connection of the interface
+// and re init it if the connection is broken
+func (c *CCU) superviseInterface(ctx context.Context, id string, client rpc.Client) {
+	state, err := c.ping(ctx, id, client)
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.clientMutex.Lock()
+	status := c.interfaceStatus[id]
+	status.State = state
+	status.Error = err
+	switch state {
+	case ConnectionConnected:
+		status.LastPong = time.Now()
+	case ConnectionUnreachable:
+		status.Available = false
+	}
+	c.interfaceStatus[id] = status
+	c.clientMutex.Unlock()
+
+	switch state {
+	case ConnectionConnected:
+		c.supervisor.connected(id)
+		return
+
+	case ConnectionUnknown:
+		// ping not supported -> re init only if no events since some time
+		if time.Since(c.lastEvent(id)) < c.options.eventTimeout {
+			return
+		}
+
+	case ConnectionUnreachable:
+		// checked again with discovery
+		return
+	}
+
+	if !c.supervisor.allowInit(id, c.options.minReinitBackoff, c.options.maxReinitBackoff) {
+		return
+	}
+	err = c.initInterface(ctx, id, client)
+	if err != nil {
+		c.clientMutex.Lock()
+		status := c.interfaceStatus[id]
+		status.Error = err
+		c.interfaceStatus[id] = status
+		c.clientMutex.Unlock()
+		return
+	}
+	c.setLastEvent(id, time.Now())
+
+	// values may have changed while no events were received
+	if c.options.snapshotConcurrency > 0 {
+		_ = c.snapshot(ctx, id)
+	}
+}
+
+// ping the interface and wait for the PONG event
+func (c *CCU) ping(ctx context.Context, id string, client rpc.Client) (ConnectionState, error) {
+	callerID := fmt.Sprintf("%s-%d", id, atomic.AddUint64(&c.supervisor.counter, 1))
+	pong := c.supervisor.waitPong(callerID)
+	defer c.supervisor.cancelPong(callerID)
+
+	response, err := client.CallContext(ctx, "ping", []interface{}{callerID})
+	if err != nil {
+		return ConnectionUnreachable, err
+	}
+	if response.Fault != nil {
+		// ping is not supported by all interfaces
+		return ConnectionUnknown, nil
+	}
+
+	timer := time.NewTimer(c.options.pingTimeout)
+	defer timer.Stop()
+	select {
+	case <-pong:
+		return ConnectionConnected, nil
+	case <-timer.C:
+		return ConnectionBroken, errors.New("no PONG event received")
+	case <-ctx.Done():
+		return ConnectionUnknown, ctx.Err()
+	}
+}
+
+// initInterface registers the callback server on the interface
+func (c *CCU) initInterface(ctx context.Context, id string, client rpc.Client) error {
+	url, err := c.callbackURL(id, client)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.CallContext(ctx, "init", []interface{}{
+		url,
+		id,
+	})
+	if err != nil {
+		return err
+	}
+	if response.Fault != nil {
+		return response.Fault
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package homematic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/bboehmke/homematic/rpc"
+	"gitlab.com/bboehmke/homematic/script"
+)
+
+func TestConnectionState_String(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal("unknown", ConnectionUnknown.String())
+	ass.Equal("connected", ConnectionConnected.String())
+	ass.Equal("broken", ConnectionBroken.String())
+	ass.Equal("unreachable", ConnectionUnreachable.String())
+}
+
+func TestBackoff(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal(time.Second, backoff(0, time.Second, time.Minute))
+	ass.Equal(time.Second*2, backoff(1, time.Second, time.Minute))
+	ass.Equal(time.Second*32, backoff(5, time.Second, time.Minute))
+	ass.Equal(time.Minute, backoff(6, time.Second, time.Minute))
+	ass.Equal(time.Minute, backoff(1000, time.Second, time.Minute))
+}
+
+func TestSupervisor_allowInit(t *testing.T) {
+	ass := assert.New(t)
+
+	s := newSupervisor()
+	ass.True(s.allowInit("test", time.Hour, time.Hour))
+	ass.False(s.allowInit("test", time.Hour, time.Hour))
+	ass.True(s.allowInit("other", time.Hour, time.Hour))
+
+	s.connected("test")
+	ass.True(s.allowInit("test", time.Hour, time.Hour))
+}
+
+func TestCCU_superviseInterfaces(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCUWithOptions("127.0.0.1",
+		WithPingTimeout(time.Millisecond*50),
+		WithReinitBackoff(time.Hour, time.Hour),
+		WithEventTimeout(time.Minute))
+	ass.NoError(err)
+
+	var mutex sync.Mutex
+	inits := make(map[string]int)
+	countInit := func(id string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		inits[id]++
+	}
+
+	ccu.rpcClients = map[string]rpc.Client{
+		// healthy interface
+		"connected": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			switch method {
+			case "ping":
+				go ccu.handleCallback("event", []interface{}{
+					"connected", "CENTRAL", "PONG", params[0],
+				})
+			case "init":
+				countInit("connected")
+			}
+			return &rpc.Response{}, nil
+		}),
+		// callback path broken -> no PONG
+		"broken": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			if method == "init" {
+				countInit("broken")
+			}
+			return &rpc.Response{}, nil
+		}),
+		// interface not reachable
+		"unreachable": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			if method == "init" {
+				countInit("unreachable")
+			}
+			return nil, errors.New("connection refused")
+		}),
+		// ping not supported
+		"noping": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			switch method {
+			case "ping":
+				return &rpc.Response{
+					Fault: &rpc.Fault{Code: -1, String: "unknown method"},
+				}, nil
+			case "init":
+				countInit("noping")
+			}
+			return &rpc.Response{}, nil
+		}),
+	}
+	ccu.setLastEvent("noping", time.Now())
+
+	ccu.superviseInterfaces(context.Background())
+
+	interfaces := ccu.Interfaces()
+	ass.Equal(ConnectionConnected, interfaces["connected"].State)
+	ass.False(interfaces["connected"].LastPong.IsZero())
+	ass.Equal(ConnectionBroken, interfaces["broken"].State)
+	ass.EqualError(interfaces["broken"].Error, "no PONG event received")
+	ass.Equal(ConnectionUnreachable, interfaces["unreachable"].State)
+	ass.False(interfaces["unreachable"].Available)
+	ass.Equal(ConnectionUnknown, interfaces["noping"].State)
+
+	// only broken interface is registered again
+	ass.Equal(map[string]int{"broken": 1}, inits)
+
+	// re init only after backoff
+	ccu.setLastEvent("noping", time.Time{})
+	ccu.superviseInterfaces(context.Background())
+	ass.Equal(map[string]int{"broken": 1, "noping": 1}, inits)
+	ass.Equal(ConnectionBroken, ccu.Interfaces()["broken"].State)
+}
+
+func TestCCU_callbackEvent_pong(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := ccu.Events(ctx, EventFilter{})
+
+	pong := ccu.supervisor.waitPong("test-1")
+	_, fault := ccu.callbackEvent([]interface{}{
+		"test", "CENTRAL", "PONG", "test-2",
+	})
+	ass.Nil(fault)
+	_, fault = ccu.callbackEvent([]interface{}{
+		"test", "CENTRAL", "PONG", "test-1",
+	})
+	ass.Nil(fault)
+
+	select {
+	case <-pong:
+	default:
+		ass.Fail("PONG not received")
+	}
+	select {
+	case <-pong:
+		ass.Fail("unexpected PONG")
+	default:
+	}
+	ccu.supervisor.cancelPong("test-1")
+	ass.Empty(ccu.supervisor.pongs)
+
+	// PONG of own pings is not published
+	ass.Len(events, 0)
+	_, fault = ccu.callbackEvent([]interface{}{
+		"test", "CENTRAL", "PONG", "other",
+	})
+	ass.Nil(fault)
+	ass.Len(events, 1)
+	event := <-events
+	ass.Equal("other", event.Value)
+}
+
+func TestSupervisor_unknownAddress(t *testing.T) {
+	ass := assert.New(t)
+
+	s := newSupervisor()
+	s.unknownAddress("a", time.Minute)
+	s.unknownAddress("b", time.Minute)
+	ass.Len(s.reconcile, 1)
+	<-s.reconcile
+
+	// address requested within interval is ignored
+	s.unknownAddress("a", time.Minute)
+	ass.Len(s.reconcile, 0)
+
+	s.unknownAddress("a", 0)
+	ass.Len(s.reconcile, 1)
+
+	// addresses with elapsed interval are removed
+	ass.Len(s.unknown, 1)
+	ass.Contains(s.unknown, "a")
+}
+
+func TestSupervisor_deviceUpdate(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	var updates []int
+	for i := 0; i < 3; i++ {
+		i := i
+		ccu.supervisor.deviceUpdate(func(ctx context.Context) {
+			updates = append(updates, i)
+		})
+	}
+	ass.Len(ccu.supervisor.updateReady, 1)
+	ass.Empty(updates)
+
+	// updates are executed in order of the calls
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ccu.reconcileDevices(ctx)
+	}()
+	for len(ccu.supervisor.updateReady) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	ass.Equal([]int{0, 1, 2}, updates)
+	ass.Empty(ccu.supervisor.takeUpdates())
+}
+
+func TestCCU_reconcileDevices(t *testing.T) {
+	ass := assert.New(t)
+
+	ccu, err := NewCCU("127.0.0.1")
+	ass.NoError(err)
+
+	listed := make(chan struct{})
+	blocked := make(chan struct{})
+	ccu.rpcClients = map[string]rpc.Client{
+		"test": testRpcClient(func(method string, params []interface{}) (*rpc.Response, error) {
+			ass.Equal("listDevices", method)
+			close(listed)
+			<-blocked
+			return &rpc.Response{
+				Params: []interface{}{[]interface{}{
+					map[string]interface{}{"ADDRESS": "address"},
+				}},
+			}, nil
+		}),
+	}
+	ccu.scriptClient = testScriptClient(func(script string) (script.Result, error) {
+		return map[string]string{"output": "address=name\n"}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ccu.reconcileDevices(ctx)
+	}()
+
+	// event of unknown device does not block
+	_, fault := ccu.callbackEvent([]interface{}{
+		"test", "address", "STATE", true,
+	})
+	ass.Nil(fault)
+	<-listed
+
+	// events are handled while device list is loaded
+	_, fault = ccu.callbackEvent([]interface{}{
+		"test", "address", "STATE", false,
+	})
+	ass.Nil(fault)
+	close(blocked)
+
+	known := func() bool {
+		ccu.deviceMutex.RLock()
+		defer ccu.deviceMutex.RUnlock()
+		return ccu.devices["address"] != nil
+	}
+	for i := 0; i < 100 && !known(); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	ass.True(known())
+
+	cancel()
+	<-done
+}